@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// loadArchive reads a file written by --format json back into results for
+// --from-json. The file is checked against the output schema first, so a
+// file from another tool or an incompatible version fails on the offending
+// field instead of rendering half-empty restaurants.
+func loadArchive(path string) ([]areaResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read --from-json file: %w", err)
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("could not parse --from-json file %s: %w", path, err)
+	}
+	// With --summary-json the areas sit next to the summary.
+	if wrapped, ok := raw.(map[string]any); ok && wrapped["areas"] != nil {
+		raw = wrapped["areas"]
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("could not parse --from-json file %s: %w", path, err)
+		}
+	}
+	if err := validateSchema(raw, typeSchema(reflect.TypeOf([]jsonArea{})), "$"); err != nil {
+		return nil, fmt.Errorf("%s does not match the --format json schema: %w", path, err)
+	}
+	var areas []jsonArea
+	if err := json.Unmarshal(data, &areas); err != nil {
+		return nil, fmt.Errorf("could not parse --from-json file %s: %w", path, err)
+	}
+
+	results := make([]areaResult, 0, len(areas))
+	for i, area := range areas {
+		result, err := fromJSONArea(area)
+		if err != nil {
+			return nil, fmt.Errorf("%s: $[%d]: %w", path, i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// fromJSONArea is the inverse of toJSONArea.
+func fromJSONArea(in jsonArea) (areaResult, error) {
+	result := areaResult{
+		Area:        AreaConfig{City: in.City, Area: in.Area},
+		Restaurants: in.Restaurants,
+		Info: SourceInfo{
+			Source:   in.Source,
+			Warning:  in.Warning,
+			URL:      in.URL,
+			FinalURL: in.FinalURL,
+		},
+	}
+	if in.CacheUpdated != nil {
+		result.Info.CacheUpdated = *in.CacheUpdated
+	}
+	if len(in.Days) > 0 {
+		for _, label := range in.Days {
+			day, ok := parseDayFlag(label)
+			if !ok {
+				return result, fmt.Errorf("invalid day %q", label)
+			}
+			result.Days = append(result.Days, day)
+		}
+		result.Info.Label = fmt.Sprintf("%s (days %s)", areaLabel(result.Area), strings.Join(in.Days, ", "))
+	} else {
+		day, ok := parseDayFlag(in.Day)
+		if !ok {
+			return result, fmt.Errorf("invalid day %q", in.Day)
+		}
+		result.Day = day
+		result.Info.Label = areaLabel(result.Area)
+	}
+
+	// The dish lines are not part of the JSON; --dish-price needs them to
+	// drop dishes from the menu.
+	for i := range result.Restaurants {
+		r := &result.Restaurants[i]
+		for j := range r.Items {
+			for _, line := range r.Menu {
+				if strings.HasPrefix(line, r.Items[j].Dish) && strings.HasSuffix(strings.TrimSuffix(line, "."), r.Items[j].Price) {
+					r.Items[j].Line = line
+					break
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// archivedAreas lists the distinct areas in results, in order.
+func archivedAreas(results []areaResult) []AreaConfig {
+	seen := map[string]bool{}
+	var areas []AreaConfig
+	for _, result := range results {
+		if label := areaLabel(result.Area); !seen[label] {
+			seen[label] = true
+			areas = append(areas, result.Area)
+		}
+	}
+	return areas
+}
+
+// keepArchivedAreas drops the results for areas not in areas, e.g. after
+// --areas-match or --exclude-area.
+func keepArchivedAreas(results []areaResult, areas []AreaConfig) []areaResult {
+	keep := map[string]bool{}
+	for _, area := range areas {
+		keep[areaLabel(area)] = true
+	}
+	var kept []areaResult
+	for _, result := range results {
+		if keep[areaLabel(result.Area)] {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
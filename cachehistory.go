@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat stamps cache history file names; it sorts by time.
+const snapshotTimeFormat = "20060102T150405"
+
+// snapshotPattern globs the history of a cached page: name.html becomes
+// name.<timestamp>.html.
+func snapshotPattern(dir, name string) (string, string) {
+	ext := filepath.Ext(name)
+	return filepath.Join(dir, strings.TrimSuffix(name, ext)+"."), ext
+}
+
+// saveSnapshot copies the cached page just written for name into a
+// timestamped file next to it and prunes all but the newest keep snapshots.
+func saveSnapshot(dir, name string, keep int, at time.Time) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		log.Printf("could not read cache for history (%s): %v", name, err)
+		return
+	}
+	prefix, ext := snapshotPattern(dir, name)
+	path := prefix + at.Format(snapshotTimeFormat) + ext
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		log.Printf("could not write cache history (%s): %v", path, err)
+		return
+	}
+
+	snapshots := listSnapshots(dir, name)
+	for len(snapshots) > keep {
+		if err := os.Remove(snapshots[0]); err != nil {
+			log.Printf("could not prune cache history (%s): %v", snapshots[0], err)
+		}
+		snapshots = snapshots[1:]
+	}
+}
+
+// listSnapshots returns the history files of name, oldest first.
+func listSnapshots(dir, name string) []string {
+	prefix, ext := snapshotPattern(dir, name)
+	matches, _ := filepath.Glob(prefix + "*" + ext)
+	var snapshots []string
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if _, err := time.Parse(snapshotTimeFormat, stamp); err == nil {
+			snapshots = append(snapshots, match)
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots
+}
+
+// runCacheHistoryList prints the kept snapshots of each area for the
+// selected day, newest first, with how many restaurants each lists. It
+// returns the exit code.
+func runCacheHistoryList(opts Options) int {
+	if opts.CacheDir == "" {
+		fmt.Fprintln(os.Stderr, "cache history needs a cache directory")
+		return 1
+	}
+	for _, area := range opts.Areas {
+		name := areaCacheName(opts.CacheNameTemplate, area, opts.Day)
+		snapshots := listSnapshots(opts.CacheDir, name)
+		fmt.Printf("%s:\n", areaLabelWithDay(area, opts.Day))
+		if len(snapshots) == 0 {
+			fmt.Println("  (no snapshots)")
+		}
+		prefix, ext := snapshotPattern(opts.CacheDir, name)
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			stamp, _ := time.ParseInLocation(snapshotTimeFormat, strings.TrimSuffix(strings.TrimPrefix(snapshots[i], prefix), ext), time.Local)
+			count := "?"
+			if data, err := os.ReadFile(snapshots[i]); err == nil {
+				if restaurants, err := parseRestaurants(bytes.NewReader(data)); err == nil {
+					count = fmt.Sprint(len(restaurants))
+				}
+			}
+			fmt.Printf("  %s  %s restaurant(s)  %s\n", stamp.Format("2006-01-02 15:04:05"), count, snapshots[i])
+		}
+	}
+	return 0
+}
+
+// previousSnapshot returns the newest snapshot of name taken before the page
+// in use was fetched at updated, or "" when there is none.
+func previousSnapshot(dir, name string, updated time.Time) string {
+	if updated.IsZero() {
+		return ""
+	}
+	prefix, ext := snapshotPattern(dir, name)
+	// The page's own snapshot is stamped in the second it was written.
+	current := prefix + updated.Format(snapshotTimeFormat) + ext
+	snapshots := listSnapshots(dir, name)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i] < current {
+			return snapshots[i]
+		}
+	}
+	return ""
+}
+
+// filterChanged keeps the restaurants whose menu differs from the previous
+// snapshot of the page, matched by ID. Restaurants missing from it, or all of
+// them when there is no snapshot, count as changed.
+func filterChanged(opts Options, area AreaConfig, updated time.Time, restaurants []Restaurant) []Restaurant {
+	name := areaCacheName(opts.CacheNameTemplate, area, opts.Day)
+	path := previousSnapshot(opts.CacheDir, name, updated)
+	if path == "" {
+		slog.Debug("no previous snapshot, every menu counts as changed", "area", areaLabel(area), "day", dayLabel(opts.Day))
+		return restaurants
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("could not read cache history (%s): %v", path, err)
+		return restaurants
+	}
+	previous, err := parseRestaurants(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("could not parse cache history (%s): %v", path, err)
+		return restaurants
+	}
+	before := make(map[string]string, len(previous))
+	for _, r := range previous {
+		before[r.ID] = menuFingerprint(cleanMenuLines(r.Menu, opts.MenuMinLine))
+	}
+
+	var changed []Restaurant
+	for _, r := range restaurants {
+		if prev, ok := before[r.ID]; !ok || prev != menuFingerprint(r.Menu) {
+			changed = append(changed, r)
+		}
+	}
+	slog.Debug("compared with previous snapshot", "area", areaLabel(area), "snapshot", filepath.Base(path), "changed", len(changed), "of", len(restaurants))
+	return changed
+}
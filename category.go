@@ -0,0 +1,75 @@
+package main
+
+import "strings"
+
+// categoryKeywords maps cuisines to dishes and words that point to them, for
+// --infer-category. Keywords are compared after normalizeToken.
+var categoryKeywords = []struct {
+	Category string
+	Keywords []string
+}{
+	{"Swedish", []string{"kottbullar", "pyttipanna", "raggmunk", "artsoppa", "kaldolmar", "husman", "stroganoff", "sill", "pannbiff", "wallenbergare", "lapskojs"}},
+	{"Japanese", []string{"sushi", "sashimi", "nigiri", "maki", "ramen", "teriyaki", "yakitori", "gyoza", "donburi", "poke"}},
+	{"Italian", []string{"pizza", "pasta", "lasagne", "lasagna", "risotto", "carbonara", "bolognese", "gnocchi", "ravioli", "tortellini", "pesto"}},
+	{"Thai", []string{"thai", "padthai", "tomyum", "massaman", "pananeng", "khaopad", "gronkurry"}},
+	{"Indian", []string{"tikka", "masala", "korma", "tandoori", "naan", "biryani", "vindaloo", "dal", "paneer"}},
+	{"Chinese", []string{"wok", "dimsum", "dumplings", "kungpao", "sweetandsour", "sursot"}},
+	{"Vietnamese", []string{"pho", "banhmi", "bunbo"}},
+	{"Mexican", []string{"taco", "tacos", "burrito", "quesadilla", "nachos", "enchilada", "fajitas"}},
+	{"Middle Eastern", []string{"falafel", "hummus", "shawarma", "kebab", "meze", "tabbouleh"}},
+	{"Burgers", []string{"burger", "hamburgare", "cheeseburger"}},
+}
+
+// inferCategory guesses a cuisine from the restaurant's name and menu: the
+// category with the most keyword hits wins, ties going to the earlier one,
+// so a husman menu with one lasagne stays Swedish.
+func inferCategory(r Restaurant) string {
+	var words []string
+	for _, text := range append([]string{r.Name}, r.Menu...) {
+		for _, field := range strings.Fields(text) {
+			if word := normalizeToken(field); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+	// Multi-word dishes such as "pad thai" are matched without spaces too.
+	joined := strings.Join(words, "")
+
+	best, bestHits := "", 0
+	for _, entry := range categoryKeywords {
+		hits := 0
+		for _, keyword := range entry.Keywords {
+			if containsKeyword(words, joined, keyword) {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = entry.Category, hits
+		}
+	}
+	return best
+}
+
+// containsKeyword matches whole words, compounds ending in a longer keyword
+// (laxsushi) and multi-word dishes found in joined.
+func containsKeyword(words []string, joined, keyword string) bool {
+	for _, word := range words {
+		if word == keyword || len(keyword) > 4 && strings.HasSuffix(word, keyword) {
+			return true
+		}
+	}
+	return len(keyword) > 5 && strings.Contains(joined, keyword)
+}
+
+// filterCategory keeps restaurants whose category contains query, ignoring
+// case and accents.
+func filterCategory(restaurants []Restaurant, query string) []Restaurant {
+	normQuery := normalizeToken(query)
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		if r.Category != "" && strings.Contains(normalizeToken(r.Category), normQuery) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
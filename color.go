@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI styles used in text output.
+const (
+	styleBold   = "1"
+	styleYellow = "33"
+)
+
+// colorEnabled is set once from --color before any output is printed. Every
+// styled line goes through colorize, so this is the single switch.
+var colorEnabled bool
+
+// resolveColor decides whether to emit ANSI codes. auto honours NO_COLOR
+// (https://no-color.org) and only colors a terminal.
+func resolveColor(mode string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", colorAuto:
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
+		}
+		if os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		return stdoutIsTerminal(), nil
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid --color %q (use auto, always or never)", mode)
+	}
+}
+
+func colorize(text, style string) string {
+	if !colorEnabled || text == "" {
+		return text
+	}
+	return "\x1b[" + style + "m" + text + "\x1b[0m"
+}
+
+// printStyledLine wraps like printLine, then styles each wrapped line so the
+// escape codes never count toward the width.
+func printStyledLine(line, style string) {
+	for _, wrapped := range wrappedLines(line) {
+		fmt.Fprintln(output, colorize(wrapped, style))
+	}
+}
+
+// stdoutIsTerminal reports whether results are shown to a person rather
+// than piped or redirected.
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runConfig handles `config init|check|migrate`.
+func runConfig(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "usage: config init|check|migrate [--config path]")
+		os.Exit(2)
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("config "+sub, flag.ExitOnError)
+	var path string
+	fs.StringVar(&path, "config", defaultConfigPath(), "Path to YAML config")
+	fs.StringVar(&path, "f", defaultConfigPath(), "Short for --config")
+	fs.Parse(args)
+
+	switch sub {
+	case "init":
+		promptAndSaveConfig(path)
+	case "check":
+		cfg, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		problems := checkConfig(cfg)
+		if len(problems) > 0 {
+			fmt.Printf("Config %s has problems:\n", expandHome(path))
+			for _, p := range problems {
+				fmt.Printf("  - %s\n", p)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Config %s is valid (%d area(s)).\n", expandHome(path), len(configAreas(cfg)))
+	case "migrate":
+		cfg, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !migrateConfig(cfg) {
+			fmt.Println("Config is already up to date.")
+			return
+		}
+		if err := saveConfig(path, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Migrated config %s.\n", expandHome(path))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q (use init, check or migrate)\n", sub)
+		os.Exit(2)
+	}
+}
+
+// configTemplate is written by --edit-config when there is no config yet.
+const configTemplate = `# kvartersmenyn-cli config. Run "kvartersmenyn-cli config check" after editing.
+
+# City segment from the kvartersmenyn URL, e.g. goteborg.
+city: goteborg
+
+# Areas to fetch; each may set its own city.
+areas:
+  - area: garda_161
+  # - city: stockholm
+  #   area: ostermalm_42
+
+# Cached pages are reused for cache_ttl (a Go duration, hours or "auto").
+# cache_dir defaults to the platform cache directory.
+# cache_dir: ~/.cache/kvartersmenyn
+cache_ttl: 6h
+
+# Restaurants never to show (fuzzy-matched like --name).
+# blocklist:
+#   - Max Hamburgare
+
+# Runs with more areas than this ask for confirmation (or --yes).
+# max_areas: 20
+
+# Keep this many timestamped copies of each cached page (see --cache-history-list).
+# cache_history: 5
+
+# Extra words to leave out of --top-words.
+# stop_words:
+#   - husets
+
+# Default for --format: text, json, ical, vcard or debug.
+# output_format: text
+`
+
+// runEditConfig opens the config in the user's editor, creating it from
+// configTemplate first when missing, and validates it afterwards. It returns
+// the exit code.
+func runEditConfig(path string) int {
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "no config path available")
+		return 1
+	}
+	if isConfigURL(path) {
+		fmt.Fprintf(os.Stderr, "config %s is a URL and cannot be edited here\n", path)
+		return 1
+	}
+	path = expandHome(path)
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "could not create config directory: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(path, []byte(configTemplate), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write config template (%s): %v\n", path, err)
+			return 1
+		}
+	}
+
+	editor := strings.Fields(firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), defaultEditor()))
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not run editor %q: %v\n", editor[0], err)
+		return 1
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if problems := checkConfig(cfg); len(problems) > 0 {
+		fmt.Printf("Config %s has problems:\n", path)
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		return 1
+	}
+	fmt.Printf("Config %s is valid (%d area(s)).\n", path, len(configAreas(cfg)))
+	return 0
+}
+
+func defaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// runCache handles `cache list|clear`.
+func runCache(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "usage: cache list|clear [--cache-dir dir] [--config path]")
+		os.Exit(2)
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("cache "+sub, flag.ExitOnError)
+	var dir, path string
+	fs.StringVar(&dir, "cache-dir", "", "Directory for cached HTML")
+	fs.StringVar(&dir, "C", "", "Short for --cache-dir")
+	fs.StringVar(&path, "config", defaultConfigPath(), "Path to YAML config")
+	fs.StringVar(&path, "f", defaultConfigPath(), "Short for --config")
+	fs.Parse(args)
+
+	if dir == "" {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		dir = firstNonEmpty(cfg.CacheDir, defaultCacheDir())
+	}
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "no cache directory available")
+		os.Exit(1)
+	}
+	dir = expandHome(dir)
+
+	files, err := cacheFiles(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch sub {
+	case "list":
+		if len(files) == 0 {
+			fmt.Printf("No cached pages in %s.\n", dir)
+			return
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			fmt.Printf("%s  %7d bytes  %s\n", info.ModTime().Local().Format("2006-01-02 15:04"), info.Size(), filepath.Base(file))
+		}
+	case "clear":
+		removed := 0
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				fmt.Fprintf(os.Stderr, "could not remove %s: %v\n", file, err)
+				continue
+			}
+			removed++
+		}
+		fmt.Printf("Removed %d cached page(s) from %s.\n", removed, dir)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown cache command %q (use list or clear)\n", sub)
+		os.Exit(2)
+	}
+}
+
+// cacheFiles returns the cached HTML pages and sidecars in dir, sorted by name.
+func cacheFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.html", "*" + fingerprintSuffix, "*" + emptyMarkerSuffix, "*" + freshnessSuffix} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not read cache directory (%s): %w", dir, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// selfTestArea is a busy area that reliably lists restaurants on weekdays.
+var selfTestArea = AreaConfig{City: "goteborg", Area: "garda_161"}
+
+// runSelfTest fetches selfTestArea live (bypassing the cache) and checks that
+// the parser still finds restaurants. It returns the process exit code.
+func runSelfTest() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Wednesday always has a full lunch listing, unlike weekends.
+	const day = 3
+	url := buildAreaURL(selfTestArea.City, selfTestArea.Area, day)
+	resp, err := fetchHTML(ctx, url, Options{})
+	if err != nil {
+		fmt.Printf("FAIL: could not fetch %s: %v\n", url, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	restaurants, err := parseRestaurants(resp.Body)
+	if err != nil {
+		fmt.Printf("FAIL: could not parse %s: %v\n", url, err)
+		return 1
+	}
+
+	complete := 0
+	for _, r := range restaurants {
+		if r.Name != "" && r.Price != "" {
+			complete++
+		}
+	}
+	if complete == 0 {
+		fmt.Printf("FAIL: %s yielded %d restaurant(s), none with both name and price\n", url, len(restaurants))
+		return 1
+	}
+	fmt.Printf("PASS: %s yielded %d restaurant(s), %d with name and price\n", url, len(restaurants), complete)
+	return 0
+}
+
+// runDiscover lists the areas linked from a city's page so they can be
+// pasted into config or passed to --area. It returns the exit code.
+func runDiscover(opts Options, city string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	areas, _, err := discoverAreas(ctx, opts, city)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not discover areas for %s: %v\n", city, err)
+		return 1
+	}
+	if len(areas) == 0 {
+		fmt.Printf("No areas found for %s.\n", city)
+		return 1
+	}
+
+	width := 0
+	for _, area := range areas {
+		if n := len([]rune(area.Name)); n > width {
+			width = n
+		}
+	}
+	fmt.Printf("Areas in %s:\n\n", city)
+	for _, area := range areas {
+		fmt.Printf("  %-*s  %s\n", width, area.Name, area.Slug)
+	}
+	fmt.Println("\nConfig snippet:")
+	fmt.Printf("\ncity: %s\nareas:\n", city)
+	for _, area := range areas {
+		fmt.Printf("  - area: %s # %s\n", area.Slug, area.Name)
+	}
+	return 0
+}
+
+// discoverAreas fetches (cache-first) and parses a city's area directory.
+func discoverAreas(ctx context.Context, opts Options, city string) ([]AreaLink, SourceInfo, error) {
+	cacheName := areaDirectoryCacheName(city)
+	info := SourceInfo{Label: city, Source: "cache"}
+	ttl := effectiveCacheTTL(opts, weekdayToDay(time.Now().Weekday()), time.Now())
+	reader, modTime, ok := tryCache(opts.CacheDir, cacheName, ttl)
+	if ok {
+		info.CacheUpdated = modTime
+	} else {
+		resp, err := fetchHTML(ctx, buildCityURL(city, weekdayToDay(time.Now().Weekday())), opts)
+		if err != nil {
+			return nil, SourceInfo{}, err
+		}
+		reader, info.CacheUpdated, err = cacheAndWrap(resp.Body, opts.CacheDir, cacheName)
+		if err != nil {
+			return nil, SourceInfo{}, err
+		}
+		info.Source = "live"
+	}
+	defer reader.Close()
+
+	areas, err := parseAreaLinks(reader, city)
+	return areas, info, err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// minCompareDaysColumn is the narrowest day column --compare-days uses; a
+// narrower terminal still gets it, wrapped past the right edge.
+const minCompareDaysColumn = 12
+
+// printCompareDays pivots a --week run around each matched restaurant: one
+// table per restaurant with a column per day and that day's price and menu.
+// A day the restaurant was not listed on is a blank cell. Restaurants are
+// matched across areas by ID, in the order first seen.
+func printCompareDays(results []areaResult, opts Options, nameQuery, menuQuery, combinedQuery string) {
+	var days []int
+	seenDay := map[int]bool{}
+	var order []string
+	byID := map[string]map[int]Restaurant{}
+	areas := map[string][]string{}
+	for _, result := range results {
+		if !seenDay[result.Day] {
+			seenDay[result.Day] = true
+			days = append(days, result.Day)
+		}
+		for _, r := range result.Restaurants {
+			if byID[r.ID] == nil {
+				byID[r.ID] = map[int]Restaurant{}
+				order = append(order, r.ID)
+			}
+			if _, ok := byID[r.ID][result.Day]; !ok {
+				byID[r.ID][result.Day] = r
+			}
+			if label := areaLabel(result.Area); !slices.Contains(areas[r.ID], label) {
+				areas[r.ID] = append(areas[r.ID], label)
+			}
+		}
+	}
+	if len(order) == 0 {
+		noHitMsg(nameQuery, menuQuery, combinedQuery, strings.TrimSpace(opts.Address))
+		return
+	}
+
+	column := max((terminalWidth()-3*(len(days)-1))/len(days), minCompareDaysColumn)
+	now := time.Now()
+	for i, id := range order {
+		if i > 0 {
+			fmt.Fprintln(output)
+		}
+		var name string
+		for _, day := range days {
+			if r, ok := byID[id][day]; ok {
+				name = r.Name
+				break
+			}
+		}
+		printStyledLine(fmt.Sprintf("%s — %s", name, strings.Join(areas[id], ", ")), styleBold)
+		fmt.Fprintln(output)
+
+		headings := make([]string, len(days))
+		rules := make([]string, len(days))
+		cells := make([][]string, len(days))
+		height := 0
+		for j, day := range days {
+			headings[j] = dayDate(day, now).Format("Mon 2 Jan")
+			rules[j] = strings.Repeat("─", column)
+			if r, ok := byID[id][day]; ok {
+				cells[j] = captureLines(column, func() {
+					if price := formatPrice(r, opts.PriceFormat); price != "" {
+						printLine(price)
+					}
+					for _, line := range r.Menu {
+						printLine("- " + line)
+					}
+				})
+			}
+			height = max(height, len(cells[j]))
+		}
+		printCompareDaysRow(headings, column)
+		fmt.Fprintln(output, strings.Join(rules, "─┼─"))
+		for line := 0; line < height; line++ {
+			row := make([]string, len(days))
+			for j := range days {
+				if line < len(cells[j]) {
+					row[j] = cells[j][line]
+				}
+			}
+			printCompareDaysRow(row, column)
+		}
+	}
+}
+
+func printCompareDaysRow(cells []string, column int) {
+	padded := make([]string, len(cells))
+	for i, cell := range cells {
+		padded[i] = fmt.Sprintf("%-*s", column, cell)
+	}
+	fmt.Fprintln(output, strings.TrimRight(strings.Join(padded, " │ "), " "))
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,14 +27,56 @@ type Config struct {
 	Areas    []AreaConfig `yaml:"areas,omitempty"`
 	CacheDir string       `yaml:"cache_dir"`
 	CacheTTL string       `yaml:"cache_ttl"`
+	// PriceCurrency is assumed for prices without a currency marker.
+	PriceCurrency string  `yaml:"price_currency,omitempty"`
+	EURRate       float64 `yaml:"eur_rate,omitempty"`
+	// PriceBuckets are the lower bounds (SEK) of the --buckets ranges.
+	PriceBuckets []float64 `yaml:"price_buckets,omitempty"`
+	// HTTPHeaders are sent with every request, e.g. a cookie for a gateway.
+	HTTPHeaders map[string]string `yaml:"http_headers,omitempty"`
+	// Postcodes maps postcodes (or prefixes) to city/area labels for --postcode.
+	Postcodes map[string][]string `yaml:"postcodes,omitempty"`
+	// OutputFormat is used when --format is not given.
+	OutputFormat string `yaml:"output_format,omitempty"`
+	// MenuPostedHour is the hour before which today's menu may still be
+	// yesterday's. Zero means the default.
+	MenuPostedHour int `yaml:"menu_posted_hour,omitempty"`
+	// MenuMinLineLength drops shorter menu fragments. Zero means the default.
+	MenuMinLineLength int `yaml:"menu_min_line_length,omitempty"`
+	// TranslateCmd reads a menu line on stdin and prints its translation,
+	// used by --translate.
+	TranslateCmd string `yaml:"translate_cmd,omitempty"`
+	// Blocklist names restaurants that are never shown (fuzzy-matched).
+	Blocklist []string `yaml:"blocklist,omitempty"`
+	// CityDefaultAreas lists the areas a bare --city expands to, per city.
+	CityDefaultAreas map[string][]string `yaml:"city_default_areas,omitempty"`
+	// MaxAreas is how many areas a run may fetch before asking for
+	// confirmation. Zero means the default.
+	MaxAreas int `yaml:"max_areas,omitempty"`
+	// CacheHistory keeps this many timestamped copies of each cached page.
+	CacheHistory int `yaml:"cache_history,omitempty"`
+	// StopWords are left out of --top-words on top of the built-in list.
+	StopWords []string `yaml:"stop_words,omitempty"`
 }
 
 // AreaConfig is one target: either a whole city or a specific area.
 type AreaConfig struct {
 	City string `yaml:"city,omitempty"`
 	Area string `yaml:"area,omitempty"`
+	// Headers are sent with this area's requests only, on top of (and
+	// overriding) the global headers.
+	Headers map[string]string `yaml:"headers,omitempty"`
 }
 
+// defaultMenuMinLine drops one-character menu fragments.
+const defaultMenuMinLine = 2
+
+// defaultMaxAreas guards against configs that expand to many live requests.
+const defaultMaxAreas = 20
+
+// defaultMenuPostedHour is when most restaurants have posted today's menu.
+const defaultMenuPostedHour = 10
+
 func defaultCacheDir() string {
 	home, _ := os.UserHomeDir()
 	switch runtime.GOOS {
@@ -66,24 +117,36 @@ func defaultConfigPath() string {
 	return filepath.Join(base, "config.yaml")
 }
 
-// loadConfig returns an empty config when the file is missing.
+// loadConfig returns an empty config when the file is missing. An https
+// URL is fetched and cached, see loadRemoteConfig.
 func loadConfig(path string) (*Config, error) {
 	if path == "" {
 		return &Config{}, nil
 	}
-	path = expandHome(path)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return &Config{}, nil
+	var data []byte
+	var err error
+	if isConfigURL(path) {
+		if data, err = loadRemoteConfig(path); err != nil {
+			return nil, err
+		}
+	} else {
+		path = expandHome(path)
+		data, err = os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return &Config{}, nil
+			}
+			return nil, fmt.Errorf("could not read config (%s): %w", path, err)
 		}
-		return nil, fmt.Errorf("could not read config (%s): %w", path, err)
 	}
 
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("could not parse config (%s): %w", path, err)
 	}
+	if isConfigURL(path) {
+		dropUntrustedKeys(&cfg, path)
+	}
 	return &cfg, nil
 }
 
@@ -94,6 +157,9 @@ func saveConfig(path string, cfg *Config) error {
 	if path == "" {
 		return errors.New("no config path available")
 	}
+	if isConfigURL(path) {
+		return fmt.Errorf("config %s is a URL and cannot be written; edit the shared file instead", path)
+	}
 
 	path = expandHome(path)
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
@@ -158,15 +224,409 @@ func mergeOptions(cfg *Config, flags Flags) (Options, error) {
 		Name:     strings.TrimSpace(flags.Name),
 		Search:   strings.TrimSpace(flags.Search),
 		Menu:     strings.TrimSpace(flags.Menu),
+		EURRate:  defaultEURRate,
+		SaveHTML: strings.TrimSpace(flags.SaveHTML),
+		Buckets:  flags.Buckets,
+		Explain:  flags.Explain,
+		// --continue is the default in a terminal; scripts, cron jobs and
+		// pipelines keep stopping at the first failure.
+		FailFast:    flags.FailFast || (!flags.Continue && !stdoutIsTerminal()),
+		StrictParse: flags.StrictParse,
+	}
+
+	if flags.Format != "" {
+		format, ok := parseFormat(flags.Format)
+		if !ok {
+			return opts, fmt.Errorf("invalid --format %q (use %s)", flags.Format, strings.Join(outputFormats, ", "))
+		}
+		opts.Format = format
+	} else if format, ok := parseFormat(cfg.OutputFormat); ok {
+		opts.Format = format
+	} else {
+		log.Printf("unknown output_format %q in config, using text", cfg.OutputFormat)
+		opts.Format = formatText
+	}
+
+	if flags.JSONStream {
+		if flags.Format != "" && opts.Format != formatJSON {
+			return opts, fmt.Errorf("--json-stream cannot be combined with --format %s", opts.Format)
+		}
+		opts.Format = formatJSON
+		opts.JSONStream = true
+	}
+
+	if flags.MenuLines < 0 {
+		return opts, fmt.Errorf("invalid --menu-lines %d (use 0 or more)", flags.MenuLines)
+	}
+	opts.MenuLines = flags.MenuLines
+	opts.HighlightUpdated = flags.HighlightUpdated
+	opts.ShowClosed = flags.ShowClosed
+	opts.Repeat = flags.Repeat
+	opts.ExpandSubareas = flags.ExpandSubareas
+	opts.Compare = flags.Compare
+
+	if flags.NameExact && opts.Name == "" {
+		return opts, errors.New("--name-exact needs --name")
+	}
+	opts.NameExact = flags.NameExact
+	opts.CaseSensitive = flags.CaseSensitive
+
+	if strings.TrimSpace(flags.RateLimit) != "" {
+		limit, err := parseRateLimit(flags.RateLimit)
+		if err != nil {
+			return opts, err
+		}
+		opts.Limiter = rate.NewLimiter(limit, 1)
+	}
+
+	if strings.TrimSpace(flags.MergeDays) != "" {
+		days, err := parseDayRange(flags.MergeDays)
+		if err != nil {
+			return opts, err
+		}
+		opts.MergeDays = days
+	}
+
+	if watch := strings.TrimSpace(flags.Watch); watch != "" {
+		interval, err := time.ParseDuration(watch)
+		if err != nil || interval < minWatchInterval {
+			return opts, fmt.Errorf("invalid --watch %q (use a duration of at least %s, e.g. 5m)", flags.Watch, minWatchInterval)
+		}
+		if opts.Repeat || opts.Compare || flags.Week {
+			return opts, errors.New("--watch cannot be combined with --repeat, --compare or --week")
+		}
+		opts.Watch = interval
+	}
+	if flags.ExitOnChange && opts.Watch == 0 {
+		return opts, errors.New("--exit-on-change needs --watch")
+	}
+	opts.ExitOnChange = flags.ExitOnChange
+	if flags.WatchDiff {
+		if opts.Watch == 0 {
+			return opts, errors.New("--watch-diff needs --watch")
+		}
+		if opts.Format != formatText {
+			return opts, errors.New("--watch-diff needs text output")
+		}
+		opts.WatchDiff = true
+	}
+
+	if flags.Translate {
+		if strings.TrimSpace(cfg.TranslateCmd) == "" {
+			return opts, errors.New("--translate needs translate_cmd in config (e.g. trans -b sv:en)")
+		}
+		opts.Translator = newTranslator(cfg.TranslateCmd)
+	}
+
+	if flags.Week {
+		if len(opts.MergeDays) > 0 {
+			return opts, errors.New("--week cannot be combined with --merge-days")
+		}
+		if opts.Compare {
+			return opts, errors.New("--week cannot be combined with --compare")
+		}
+		opts.Week = true
+	}
+	if opts.Format == formatICal && len(opts.MergeDays) > 0 {
+		return opts, errors.New("--format ical needs separate days; use --week instead of --merge-days")
+	}
+
+	opts.MenuMinLine = defaultMenuMinLine
+	if cfg.MenuMinLineLength > 0 {
+		opts.MenuMinLine = cfg.MenuMinLineLength
+	}
+
+	opts.MenuPostedHour = defaultMenuPostedHour
+	if cfg.MenuPostedHour != 0 {
+		if cfg.MenuPostedHour < 0 || cfg.MenuPostedHour > 23 {
+			return opts, fmt.Errorf("invalid menu_posted_hour %d (use 0-23)", cfg.MenuPostedHour)
+		}
+		opts.MenuPostedHour = cfg.MenuPostedHour
+	}
+
+	opts.MaxAreas = defaultMaxAreas
+	if cfg.MaxAreas != 0 {
+		if cfg.MaxAreas < 0 {
+			return opts, fmt.Errorf("invalid max_areas %d (use a positive number)", cfg.MaxAreas)
+		}
+		opts.MaxAreas = cfg.MaxAreas
+	}
+	opts.Yes = flags.Yes
+	opts.RespectCacheControl = flags.RespectCacheControl
+	if cfg.CacheHistory < 0 {
+		return opts, fmt.Errorf("invalid cache_history %d (use a positive number)", cfg.CacheHistory)
+	}
+	opts.CacheHistory = cfg.CacheHistory
+	if flags.ChangedOnly && (opts.CacheDir == "" || opts.CacheHistory < 2) {
+		// The newest snapshot is the page itself; comparing needs one more.
+		return opts, errors.New("--changed-only needs a cache directory and cache_history of at least 2 in the config")
+	}
+	opts.ChangedOnly = flags.ChangedOnly
+
+	switch wrap := strings.ToLower(strings.TrimSpace(flags.Wrap)); wrap {
+	case "", wrapWord:
+		opts.Wrap = wrapWord
+	case wrapOff, wrapChar:
+		opts.Wrap = wrap
+	default:
+		return opts, fmt.Errorf("invalid --wrap %q (use word, off or char)", flags.Wrap)
+	}
+
+	switch separator := strings.ToLower(strings.TrimSpace(flags.Separator)); separator {
+	case "", separatorRule:
+		opts.Separator = separatorRule
+	case separatorBlank, separatorNone:
+		opts.Separator = separator
+	default:
+		return opts, fmt.Errorf("invalid --separator %q (use rule, blank or none)", flags.Separator)
+	}
+
+	if flags.FailFast && flags.Continue {
+		return opts, errors.New("--fail-fast and --continue cannot be combined")
+	}
+
+	opts.PriceBuckets = defaultPriceBuckets
+	if len(cfg.PriceBuckets) > 0 {
+		if !sort.Float64sAreSorted(cfg.PriceBuckets) || cfg.PriceBuckets[0] <= 0 {
+			return opts, errors.New("price_buckets must be positive and in ascending order")
+		}
+		opts.PriceBuckets = cfg.PriceBuckets
+	}
+
+	currency, ok := parseCurrency(firstNonEmpty(flags.PriceCurrency, cfg.PriceCurrency))
+	if !ok {
+		return opts, fmt.Errorf("invalid price currency %q (use SEK or EUR)", firstNonEmpty(flags.PriceCurrency, cfg.PriceCurrency))
+	}
+	opts.PriceCurrency = currency
+	if cfg.EURRate > 0 {
+		opts.EURRate = cfg.EURRate
+	}
+	maxPrice, err := parseMaxPrice(flags.MaxPrice)
+	if err != nil {
+		return opts, err
+	}
+	opts.MaxPrice = maxPrice
+	if flags.DishPrice && maxPrice == 0 {
+		return opts, errors.New("--dish-price needs --max-price")
+	}
+	opts.DishPrice = flags.DishPrice
+
+	missing, err := parseMissing(flags.Missing)
+	if err != nil {
+		return opts, err
+	}
+	opts.Missing = missing
+	if flags.HasLink && slices.Contains(missing, "link") {
+		return opts, errors.New("--has-link cannot be combined with --missing link")
+	}
+	opts.HasLink = flags.HasLink
+
+	template, err := parseCacheNameTemplate(flags.CacheNameTmpl)
+	if err != nil {
+		return opts, err
+	}
+	opts.CacheNameTemplate = template
+
+	for _, entry := range cfg.Blocklist {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			opts.Blocklist = append(opts.Blocklist, entry)
+		}
+	}
+
+	order := strings.ToLower(strings.TrimSpace(flags.Sort))
+	if flags.NearNow {
+		if order != "" && order != sortNearNow {
+			return opts, fmt.Errorf("--near-now cannot be combined with --sort %s", flags.Sort)
+		}
+		order = sortNearNow
+	}
+	switch order {
+	case "", sortSite:
+		opts.Sort = sortSite
+	case sortNearNow:
+		opts.Sort = order
+	default:
+		return opts, fmt.Errorf("invalid --sort %q (use site or near-now)", flags.Sort)
+	}
+	opts.OpenNow = flags.OpenNow
+
+	if flags.LimitPerCity < 0 {
+		return opts, fmt.Errorf("invalid --limit-per-city %d (use 0 or more)", flags.LimitPerCity)
+	}
+	opts.LimitPerCity = flags.LimitPerCity
+	opts.PostProcess = strings.TrimSpace(flags.PostProcess)
+	opts.Tight = flags.Tight
+	opts.ShowURL = flags.ShowURL
+	opts.Address = strings.TrimSpace(flags.Address)
+	opts.Category = strings.TrimSpace(flags.Category)
+	// Categories are only ever inferred, so --category needs the inference.
+	opts.InferCategory = flags.InferCategory || opts.Category != ""
+
+	switch order := strings.ToLower(strings.TrimSpace(flags.SortAreas)); order {
+	case "", sortAreasConfig:
+		opts.SortAreas = sortAreasConfig
+	case sortAreasCount:
+		opts.SortAreas = order
+	default:
+		return opts, fmt.Errorf("invalid --sort-areas %q (use config or count)", flags.SortAreas)
+	}
+	// --format debug prints each area as soon as it is parsed, so options
+	// that reorder, cap or pick from the finished areas cannot apply.
+	if opts.Format == formatDebug && (opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || flags.Rotate || flags.TopWords > 0 || flags.CompareDays) {
+		return opts, errors.New("--format debug prints each area as it is parsed; it cannot be combined with --sort-areas count, --limit-per-city, --rotate, --top-words or --compare-days")
+	}
+	if dir := strings.TrimSpace(flags.OutputDir); dir != "" {
+		if opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || opts.Compare || opts.JSONStream || flags.Rotate || opts.Repeat || opts.Watch > 0 {
+			return opts, errors.New("--output-dir cannot be combined with --sort-areas count, --limit-per-city, --compare, --json-stream, --rotate, --repeat or --watch")
+		}
+		opts.OutputDir = expandHome(dir)
+	}
+	if flags.Rotate {
+		if flags.Week || len(opts.MergeDays) > 0 || opts.Compare || opts.JSONStream || opts.Repeat || opts.Watch > 0 || opts.Format == formatDebug {
+			return opts, errors.New("--rotate picks one restaurant for one day; it cannot be combined with --week, --merge-days, --compare, --json-stream, --repeat, --watch or --format debug")
+		}
+		opts.Rotate = true
+	}
+	if flags.CompareDays {
+		switch {
+		case !flags.Week:
+			return opts, errors.New("--compare-days needs --week")
+		case strings.TrimSpace(flags.Name) == "" && strings.TrimSpace(flags.Search) == "":
+			return opts, errors.New("--compare-days needs --name (or --search) to pick the restaurant")
+		case opts.Format != formatText || opts.JSONStream || opts.OutputDir != "" || opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || flags.TopWords > 0:
+			return opts, errors.New("--compare-days prints text tables; it cannot be combined with --format other than text, --json-stream, --output-dir, --sort-areas count, --limit-per-city or --top-words")
+		}
+		opts.CompareDays = true
+	}
+	if flags.TopWords < 0 {
+		return opts, fmt.Errorf("invalid --top-words %d (use 0 or more)", flags.TopWords)
+	}
+	if flags.TopWords > 0 {
+		if opts.Format != formatText && opts.Format != formatJSON || opts.JSONStream || opts.OutputDir != "" || opts.Compare || opts.Rotate ||
+			opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || opts.Repeat || opts.Watch > 0 || flags.SummaryJSON {
+			return opts, errors.New("--top-words prints one list for text or json output; it cannot be combined with --json-stream, --output-dir, --compare, --rotate, --sort-areas count, --limit-per-city, --repeat, --watch or --summary-json")
+		}
+		opts.TopWords = flags.TopWords
+		for _, word := range cfg.StopWords {
+			if word = strings.TrimSpace(word); word != "" {
+				opts.StopWords = append(opts.StopWords, word)
+			}
+		}
+	}
+	if flags.MinResults < 0 {
+		return opts, fmt.Errorf("invalid --min-results %d (use 0 or more)", flags.MinResults)
+	}
+	if flags.MinResults > 0 && (opts.Repeat || opts.Watch > 0) {
+		return opts, errors.New("--min-results checks one run; it cannot be combined with --repeat or --watch")
+	}
+	opts.MinResults = flags.MinResults
+	if flags.MetricsFile != "" {
+		if opts.Repeat || opts.Watch > 0 {
+			return opts, errors.New("--metrics-file describes one run; it cannot be combined with --repeat or --watch")
+		}
+		opts.MetricsFile = expandHome(strings.TrimSpace(flags.MetricsFile))
+	}
+	if flags.OnSuccess != "" || flags.OnEmpty != "" {
+		if opts.Repeat || opts.Watch > 0 {
+			return opts, errors.New("--on-success and --on-empty run once after a run; they cannot be combined with --repeat or --watch")
+		}
+		opts.OnSuccess, opts.OnEmpty = strings.TrimSpace(flags.OnSuccess), strings.TrimSpace(flags.OnEmpty)
+	}
+	if flags.SummaryJSON {
+		if opts.Format != formatJSON || opts.OutputDir != "" || opts.Repeat || opts.Watch > 0 {
+			return opts, errors.New("--summary-json needs --format json or --json-stream, without --output-dir, --repeat or --watch")
+		}
+		opts.SummaryJSON = true
+	}
+	if opts.JSONStream && (opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || opts.Compare || opts.Repeat || opts.Watch > 0) {
+		return opts, errors.New("--json-stream cannot be combined with --sort-areas count, --limit-per-city, --compare, --repeat or --watch")
+	}
+
+	switch format := strings.ToLower(strings.TrimSpace(flags.PriceFormat)); format {
+	case "", priceFormatRaw:
+		opts.PriceFormat = priceFormatRaw
+	case priceFormatKr, priceFormatSymbol:
+		opts.PriceFormat = format
+	default:
+		return opts, fmt.Errorf("invalid --price-format %q (use raw, kr or symbol)", flags.PriceFormat)
+	}
+
+	// Flag headers override config headers with the same key.
+	opts.Headers = map[string]string{}
+	for key, value := range cfg.HTTPHeaders {
+		key = strings.TrimSpace(key)
+		if !validHeaderKey(key) {
+			return opts, fmt.Errorf("invalid http_headers key %q in config", key)
+		}
+		opts.Headers[http.CanonicalHeaderKey(key)] = value
+	}
+	for _, raw := range flags.Headers {
+		key, value, err := parseHeader(raw)
+		if err != nil {
+			return opts, err
+		}
+		opts.Headers[http.CanonicalHeaderKey(key)] = value
+	}
+	for _, area := range cfg.Areas {
+		for key := range area.Headers {
+			if !validHeaderKey(strings.TrimSpace(key)) {
+				return opts, fmt.Errorf("invalid headers key %q for area %s in config", key, area.Area)
+			}
+		}
 	}
 
-	if len(flags.Areas) > 0 {
-		if strings.TrimSpace(flags.City) == "" {
-			return opts, errors.New("city must be provided when using --area")
+	cities := splitCities(flags.City)
+	if path := strings.TrimSpace(flags.FromJSON); path != "" {
+		if flags.Stdin || flags.Postcode != "" || len(flags.Areas) > 0 || flags.City != "" || flags.Day != "" || flags.Week || flags.MergeDays != "" {
+			return opts, errors.New("--from-json takes the areas and days from the file; use --areas-match or --exclude-area to narrow them")
+		}
+		if flags.Repeat || flags.Watch != "" || flags.ChangedOnly || flags.HighlightUpdated {
+			return opts, errors.New("--from-json cannot be combined with --repeat, --watch, --changed-only or --highlight-updated")
+		}
+		archive, err := loadArchive(expandHome(path))
+		if err != nil {
+			return opts, err
+		}
+		if len(archive) == 0 {
+			return opts, fmt.Errorf("--from-json file %s has no areas", path)
+		}
+		opts.FromJSON = expandHome(path)
+		opts.Archive = archive
+		opts.Areas = archivedAreas(archive)
+	} else if flags.Stdin {
+		if strings.TrimSpace(flags.Postcode) != "" || flags.Repeat {
+			return opts, errors.New("--stdin cannot be combined with --postcode or --repeat")
+		}
+		areas, err := readAreaTargets(os.Stdin)
+		if err != nil {
+			return opts, err
+		}
+		opts.Areas = areas
+	} else if strings.TrimSpace(flags.Postcode) != "" {
+		areas, err := lookupPostcode(flags.Postcode, cities, cfg.Postcodes)
+		if err != nil {
+			return opts, err
+		}
+		opts.Areas = areas
+	} else if len(flags.Areas) > 0 {
+		areas, err := makeAreas(cities, flags.Areas)
+		if err != nil {
+			return opts, err
+		}
+		opts.Areas = areas
+	} else if len(cities) > 0 {
+		for _, city := range cities {
+			defaults := cfg.CityDefaultAreas[city]
+			if len(defaults) == 0 {
+				opts.Areas = append(opts.Areas, AreaConfig{City: city})
+				continue
+			}
+			for _, area := range defaults {
+				if area = strings.TrimSpace(area); area != "" {
+					opts.Areas = append(opts.Areas, AreaConfig{City: city, Area: area})
+				}
+			}
 		}
-		opts.Areas = makeAreas(flags.City, flags.Areas)
-	} else if strings.TrimSpace(flags.City) != "" {
-		opts.Areas = []AreaConfig{{City: strings.TrimSpace(flags.City)}}
 	} else {
 		opts.Areas = configAreas(cfg)
 	}
@@ -174,15 +634,47 @@ func mergeOptions(cfg *Config, flags Flags) (Options, error) {
 	if len(opts.Areas) == 0 {
 		return opts, errors.New("city and area must be provided via flags or config")
 	}
+	if err := validateAreaSlugs(opts.Areas); err != nil {
+		return opts, err
+	}
+
+	if pattern := strings.TrimSpace(flags.AreasMatch); pattern != "" {
+		matched, err := matchAreas(opts.Areas, pattern)
+		if err != nil {
+			return opts, err
+		}
+		if len(matched) == 0 {
+			return opts, fmt.Errorf("no areas match --areas-match %q", pattern)
+		}
+		opts.Areas = matched
+	}
+	if len(flags.ExcludeAreas) > 0 {
+		kept, err := excludeAreas(opts.Areas, flags.ExcludeAreas)
+		if err != nil {
+			return opts, err
+		}
+		if len(kept) == 0 {
+			return opts, errors.New("--exclude-area leaves no areas to fetch")
+		}
+		opts.Areas = kept
+	}
+	if opts.FromJSON != "" {
+		opts.Archive = keepArchivedAreas(opts.Archive, opts.Areas)
+	}
 
 	// cache_ttl accepts either a full duration (6h) or just hours (6).
 	if ttlStr := firstNonEmpty(flags.CacheTTL, cfg.CacheTTL, "6h"); ttlStr != "" {
-		dur, ok := parseCacheTTL(ttlStr)
-		if ok {
+		dur, until, err := parseCacheTTL(ttlStr)
+		switch {
+		case err == nil:
 			opts.CacheTTL = dur
-		} else if flags.CacheTTL != "" {
-			return opts, fmt.Errorf("invalid --cache-ttl %q (use e.g. 6h, 1h, 48h)", flags.CacheTTL)
-		} else {
+			opts.CacheUntil = until
+		case flags.CacheTTL != "":
+			return opts, fmt.Errorf("invalid --cache-ttl %q (%v)", flags.CacheTTL, err)
+		case errors.Is(err, errNegativeCacheTTL):
+			// Unlike a typo, a negative TTL could alias auto or until:.
+			return opts, fmt.Errorf("invalid cache_ttl %q in config (%v)", cfg.CacheTTL, err)
+		default:
 			opts.CacheTTL = 6 * time.Hour
 		}
 	}
@@ -190,20 +682,78 @@ func mergeOptions(cfg *Config, flags Flags) (Options, error) {
 	return opts, nil
 }
 
-func parseCacheTTL(input string) (time.Duration, bool) {
+// autoCacheTTL is the sentinel for `cache_ttl: auto`; see effectiveCacheTTL.
+const autoCacheTTL time.Duration = -1
+
+// untilCacheTTL is the sentinel for `cache_ttl: until:HH:MM`; the time of
+// day is kept in Options.CacheUntil.
+const untilCacheTTL time.Duration = -2
+
+// effectiveCacheTTL resolves autoCacheTTL for day at now: today's menu may
+// still be posted or corrected before menu_posted_hour, so it is refetched
+// often; later today and upcoming days change less, and past days of the
+// week not at all. untilCacheTTL becomes the time since the last
+// occurrence of CacheUntil, so pages fetched before it are stale. Other
+// TTLs are returned unchanged.
+func effectiveCacheTTL(opts Options, day int, now time.Time) time.Duration {
+	if opts.CacheTTL == untilCacheTTL {
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		last := midnight.Add(opts.CacheUntil)
+		if last.After(now) {
+			last = last.AddDate(0, 0, -1)
+		}
+		return now.Sub(last)
+	}
+	if opts.CacheTTL != autoCacheTTL {
+		return opts.CacheTTL
+	}
+	today := weekdayToDay(now.Weekday())
+	switch {
+	case day == today && now.Hour() < opts.MenuPostedHour:
+		return 30 * time.Minute
+	case day == today:
+		return 2 * time.Hour
+	case day > today:
+		return 6 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// errNegativeCacheTTL rejects TTLs below zero, which would otherwise be
+// read as the autoCacheTTL and untilCacheTTL sentinels or their neighbours.
+var errNegativeCacheTTL = errors.New("must not be negative")
+
+// parseCacheTTL reads a cache_ttl. For until:HH:MM it returns
+// untilCacheTTL and the time of day as an offset from midnight.
+func parseCacheTTL(input string) (time.Duration, time.Duration, error) {
+	invalid := errors.New("use e.g. 6h, 1h, 48h, auto or until:10:00")
 	input = strings.TrimSpace(input)
 	if input == "" {
-		return 0, false
+		return 0, 0, invalid
+	}
+	if strings.EqualFold(input, "auto") {
+		return autoCacheTTL, 0, nil
+	}
+	if clock, ok := strings.CutPrefix(strings.ToLower(input), "until:"); ok {
+		at, err := time.Parse("15:04", strings.TrimSpace(clock))
+		if err != nil {
+			return 0, 0, invalid
+		}
+		return untilCacheTTL, time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute, nil
 	}
 	if dur, err := time.ParseDuration(input); err == nil {
-		return dur, true
+		if dur < 0 {
+			return 0, 0, errNegativeCacheTTL
+		}
+		return dur, 0, nil
 	}
 	if allDigits(input) {
 		if hours, err := time.ParseDuration(input + "h"); err == nil {
-			return hours, true
+			return hours, 0, nil
 		}
 	}
-	return 0, false
+	return 0, 0, invalid
 }
 
 func allDigits(input string) bool {
@@ -215,6 +765,25 @@ func allDigits(input string) bool {
 	return input != ""
 }
 
+// validSlug reports whether a city or area slug is safe to put in a cache
+// file name: it may not contain a path separator or "..".
+func validSlug(slug string) bool {
+	return !strings.ContainsAny(slug, `/\`) && !strings.Contains(slug, "..")
+}
+
+// validateAreaSlugs rejects areas whose slugs would let areaCacheName
+// point outside the cache directory.
+func validateAreaSlugs(areas []AreaConfig) error {
+	for _, area := range areas {
+		for _, slug := range []string{area.City, area.Area} {
+			if !validSlug(slug) {
+				return fmt.Errorf("invalid slug %q in area %s (must not contain /, \\ or ..)", slug, areaLabel(area))
+			}
+		}
+	}
+	return nil
+}
+
 func configAreas(cfg *Config) []AreaConfig {
 	if cfg == nil {
 		return nil
@@ -230,7 +799,7 @@ func configAreas(cfg *Config) []AreaConfig {
 		if city == "" {
 			continue
 		}
-		areas = append(areas, AreaConfig{City: city, Area: areaSlug})
+		areas = append(areas, AreaConfig{City: city, Area: areaSlug, Headers: area.Headers})
 	}
 	if len(areas) == 0 && defaultCity != "" {
 		areas = append(areas, AreaConfig{City: defaultCity, Area: strings.TrimSpace(cfg.Area)})
@@ -238,16 +807,76 @@ func configAreas(cfg *Config) []AreaConfig {
 	return areas
 }
 
-func makeAreas(city string, areas []string) []AreaConfig {
+// splitCities parses --city, which may list several cities separated by
+// commas.
+func splitCities(input string) []string {
+	var cities []string
+	for _, city := range strings.Split(input, ",") {
+		if city = strings.TrimSpace(city); city != "" {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// makeAreas pairs --area entries with cities. Entries qualified as
+// city:area carry their own city; plain slugs use the first --city.
+func makeAreas(cities []string, areas []string) ([]AreaConfig, error) {
 	var targets []AreaConfig
 	for _, area := range areas {
 		area = strings.TrimSpace(area)
 		if area == "" {
 			continue
 		}
-		targets = append(targets, AreaConfig{City: strings.TrimSpace(city), Area: area})
+		if city, slug, ok := strings.Cut(area, ":"); ok {
+			city, slug = strings.TrimSpace(city), strings.TrimSpace(slug)
+			if city == "" || slug == "" {
+				return nil, fmt.Errorf("invalid --area %q (use area or city:area)", area)
+			}
+			targets = append(targets, AreaConfig{City: city, Area: slug})
+			continue
+		}
+		if len(cities) == 0 {
+			return nil, errors.New("city must be provided when using --area (or qualify it as city:area)")
+		}
+		targets = append(targets, AreaConfig{City: cities[0], Area: area})
+	}
+	return targets, nil
+}
+
+// readAreaTargets reads one city/area or kvartersmenyn URL per line for
+// --stdin. Blank lines and lines starting with # are skipped.
+func readAreaTargets(r io.Reader) ([]AreaConfig, error) {
+	var targets []AreaConfig
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var city, area string
+		var ok bool
+		if strings.Contains(line, "kvartersmenyn.se") || strings.Contains(line, "/area/") {
+			city, area, ok = parseAreaURL(line)
+		} else {
+			city, area, ok = strings.Cut(strings.Trim(line, "/"), "/")
+			if !ok {
+				city, ok = line, true
+			}
+		}
+		city, area = strings.TrimSpace(city), strings.TrimSpace(area)
+		if !ok || city == "" || strings.Contains(area, "/") {
+			return nil, fmt.Errorf("invalid area on stdin line %d: %q (use city/area or a kvartersmenyn URL)", lineNo, line)
+		}
+		targets = append(targets, AreaConfig{City: city, Area: area})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read areas from stdin: %w", err)
 	}
-	return targets
+	if len(targets) == 0 {
+		return nil, errors.New("no areas on stdin")
+	}
+	return targets, nil
 }
 
 func firstNonEmpty(values ...string) string {
@@ -258,3 +887,164 @@ func firstNonEmpty(values ...string) string {
 	}
 	return ""
 }
+
+// checkConfig reports problems that would stop a run from using cfg.
+func checkConfig(cfg *Config) []string {
+	var problems []string
+	if len(configAreas(cfg)) == 0 {
+		problems = append(problems, "no city or areas configured")
+	}
+	for i, area := range cfg.Areas {
+		if strings.TrimSpace(area.City) == "" && strings.TrimSpace(cfg.City) == "" {
+			problems = append(problems, fmt.Sprintf("areas[%d] (%s) has no city and there is no top-level city", i, area.Area))
+		}
+	}
+	if err := validateAreaSlugs(configAreas(cfg)); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for i, entry := range cfg.Blocklist {
+		if strings.TrimSpace(entry) == "" {
+			problems = append(problems, fmt.Sprintf("blocklist[%d] is empty", i))
+		}
+	}
+	for i, word := range cfg.StopWords {
+		if strings.TrimSpace(word) == "" {
+			problems = append(problems, fmt.Sprintf("stop_words[%d] is empty", i))
+		}
+	}
+	for city, areas := range cfg.CityDefaultAreas {
+		for i, area := range areas {
+			if strings.TrimSpace(area) == "" {
+				problems = append(problems, fmt.Sprintf("city_default_areas.%s[%d] is empty", city, i))
+			}
+		}
+	}
+	if cfg.CacheTTL != "" {
+		if _, _, err := parseCacheTTL(cfg.CacheTTL); errors.Is(err, errNegativeCacheTTL) {
+			problems = append(problems, fmt.Sprintf("cache_ttl %q must not be negative", cfg.CacheTTL))
+		} else if err != nil {
+			problems = append(problems, fmt.Sprintf("cache_ttl %q is not a valid duration", cfg.CacheTTL))
+		}
+	}
+	if cfg.CacheHistory < 0 {
+		problems = append(problems, "cache_history must be positive")
+	}
+	if _, ok := parseCurrency(cfg.PriceCurrency); !ok {
+		problems = append(problems, fmt.Sprintf("price_currency %q is not SEK or EUR", cfg.PriceCurrency))
+	}
+	if cfg.EURRate < 0 {
+		problems = append(problems, "eur_rate must be positive")
+	}
+	if len(cfg.PriceBuckets) > 0 && (!sort.Float64sAreSorted(cfg.PriceBuckets) || cfg.PriceBuckets[0] <= 0) {
+		problems = append(problems, "price_buckets must be positive and in ascending order")
+	}
+	if _, ok := parseFormat(cfg.OutputFormat); !ok {
+		problems = append(problems, fmt.Sprintf("output_format %q is not one of %s", cfg.OutputFormat, strings.Join(outputFormats, ", ")))
+	}
+	if cfg.MenuPostedHour < 0 || cfg.MenuPostedHour > 23 {
+		problems = append(problems, fmt.Sprintf("menu_posted_hour %d is not between 0 and 23", cfg.MenuPostedHour))
+	}
+	for key := range cfg.HTTPHeaders {
+		if !validHeaderKey(strings.TrimSpace(key)) {
+			problems = append(problems, fmt.Sprintf("http_headers key %q is not a valid header name", key))
+		}
+	}
+	for i, area := range cfg.Areas {
+		for key := range area.Headers {
+			if !validHeaderKey(strings.TrimSpace(key)) {
+				problems = append(problems, fmt.Sprintf("areas[%d] (%s) headers key %q is not a valid header name", i, area.Area, key))
+			}
+		}
+	}
+	return problems
+}
+
+// migrateConfig moves the legacy top-level area into the areas list.
+// It reports whether cfg was changed.
+func migrateConfig(cfg *Config) bool {
+	area := strings.TrimSpace(cfg.Area)
+	if area == "" {
+		return false
+	}
+	if len(cfg.Areas) == 0 {
+		cfg.Areas = []AreaConfig{{Area: area}}
+	}
+	cfg.Area = ""
+	return true
+}
+
+// matchAreas keeps areas whose label ("city/area") matches pattern. Patterns
+// with *, ? or [ are globs; anything else is a case-insensitive substring.
+func matchAreas(areas []AreaConfig, pattern string) ([]AreaConfig, error) {
+	isGlob := strings.ContainsAny(pattern, "*?[")
+	if isGlob {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --areas-match pattern %q: %w", pattern, err)
+		}
+	}
+	lowerPattern := strings.ToLower(pattern)
+
+	var matched []AreaConfig
+	for _, area := range areas {
+		label := strings.ToLower(areaLabel(area))
+		var ok bool
+		if isGlob {
+			ok, _ = path.Match(lowerPattern, label)
+		} else {
+			ok = strings.Contains(label, lowerPattern)
+		}
+		if ok {
+			matched = append(matched, area)
+		}
+	}
+	return matched, nil
+}
+
+// excludeAreas drops areas whose slug or label ("city/area") equals one of
+// excludes, ignoring case. An exclude that matches nothing is an error so a
+// typo does not silently fetch the area anyway.
+func excludeAreas(areas []AreaConfig, excludes []string) ([]AreaConfig, error) {
+	used := make([]bool, len(excludes))
+	var kept []AreaConfig
+	for _, area := range areas {
+		excluded := false
+		for i, exclude := range excludes {
+			if strings.EqualFold(exclude, area.Area) || strings.EqualFold(exclude, areaLabel(area)) {
+				used[i] = true
+				excluded = true
+			}
+		}
+		if !excluded {
+			kept = append(kept, area)
+		}
+	}
+	for i, exclude := range excludes {
+		if !used[i] {
+			return nil, fmt.Errorf("--exclude-area %q matches no area", exclude)
+		}
+	}
+	return kept, nil
+}
+
+// parseRateLimit reads "N/unit" where unit is s, m, h or a Go duration,
+// e.g. 2/s, 30/m or 1/5s.
+func parseRateLimit(input string) (rate.Limit, error) {
+	invalid := fmt.Errorf("invalid --rate-limit %q (use e.g. 2/s, 30/m or 1/5s)", input)
+	countStr, perStr, ok := strings.Cut(strings.TrimSpace(input), "/")
+	if !ok {
+		return 0, invalid
+	}
+	count, err := strconv.ParseFloat(strings.TrimSpace(countStr), 64)
+	if err != nil || count <= 0 {
+		return 0, invalid
+	}
+	perStr = strings.TrimSpace(perStr)
+	if perStr == "s" || perStr == "m" || perStr == "h" {
+		perStr = "1" + perStr
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return 0, invalid
+	}
+	return rate.Limit(count / per.Seconds()), nil
+}
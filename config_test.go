@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCacheTTL(t *testing.T) {
+	tests := []struct {
+		input string
+		ttl   time.Duration
+		until time.Duration
+		ok    bool
+	}{
+		{"6h", 6 * time.Hour, 0, true},
+		{"90m", 90 * time.Minute, 0, true},
+		{"48", 48 * time.Hour, 0, true},
+		{"0", 0, 0, true},
+		{"0s", 0, 0, true},
+		{"auto", autoCacheTTL, 0, true},
+		{"AUTO", autoCacheTTL, 0, true},
+		{"until:10:30", untilCacheTTL, 10*time.Hour + 30*time.Minute, true},
+		{"", 0, 0, false},
+		{"abc", 0, 0, false},
+		{"until:25:00", 0, 0, false},
+		{"-1ns", 0, 0, false},
+		{"-2ns", 0, 0, false},
+		{"-6h", 0, 0, false},
+	}
+	for _, tt := range tests {
+		ttl, until, err := parseCacheTTL(tt.input)
+		if ttl != tt.ttl || until != tt.until || (err == nil) != tt.ok {
+			t.Errorf("parseCacheTTL(%q) = %v, %v, %v; want %v, %v, ok %v", tt.input, ttl, until, err, tt.ttl, tt.until, tt.ok)
+		}
+	}
+	for _, input := range []string{"-1ns", "-2ns", "-6h"} {
+		if _, _, err := parseCacheTTL(input); !errors.Is(err, errNegativeCacheTTL) {
+			t.Errorf("parseCacheTTL(%q) error = %v, want errNegativeCacheTTL", input, err)
+		}
+	}
+}
+
+func TestMergeOptionsRejectsPathSlugs(t *testing.T) {
+	for _, area := range []AreaConfig{
+		{City: "goteborg", Area: "../../etc"},
+		{City: "goteborg", Area: `..\windows`},
+		{City: "../goteborg", Area: "garda_161"},
+		{City: "goteborg", Area: "garda/161"},
+		{City: "goteborg", Area: ".."},
+	} {
+		cfg := &Config{Areas: []AreaConfig{area}}
+		if _, err := mergeOptions(cfg, Flags{}); err == nil {
+			t.Errorf("mergeOptions accepted area %s/%s", area.City, area.Area)
+		}
+	}
+	cfg := &Config{Areas: []AreaConfig{{City: "goteborg", Area: "garda_161"}}}
+	if _, err := mergeOptions(cfg, Flags{}); err != nil {
+		t.Errorf("mergeOptions rejected a plain slug: %v", err)
+	}
+}
+
+func TestMergeOptionsRejectsDebugWithBufferedOutput(t *testing.T) {
+	cfg := &Config{City: "goteborg", Area: "garda_161"}
+	for name, flags := range map[string]Flags{
+		"sort-areas":     {Format: formatDebug, SortAreas: sortAreasCount},
+		"limit-per-city": {Format: formatDebug, LimitPerCity: 2},
+		"rotate":         {Format: formatDebug, Rotate: true},
+		"top-words":      {Format: formatDebug, TopWords: 5},
+	} {
+		if _, err := mergeOptions(cfg, flags); err == nil {
+			t.Errorf("--format debug with --%s was accepted", name)
+		}
+	}
+	if _, err := mergeOptions(cfg, Flags{Format: formatDebug}); err != nil {
+		t.Errorf("--format debug alone: %v", err)
+	}
+}
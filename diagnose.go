@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// siteHost is the host every page is fetched from.
+const siteHost = "www.kvartersmenyn.se"
+
+// runDiagnose checks the environment a run depends on (DNS, connectivity,
+// proxy, config and cache paths) and prints one line per check. It returns 1
+// when any check failed.
+func runDiagnose(flags Flags, cfg *Config, cfgErr error) int {
+	failed := false
+	report := func(status, check, detail string) {
+		if status == "FAIL" {
+			failed = true
+		}
+		fmt.Printf("%-4s  %-13s %s\n", status, check, detail)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, siteHost)
+	if err != nil {
+		report("FAIL", "DNS", fmt.Sprintf("could not resolve %s: %v", siteHost, err))
+	} else {
+		report("OK", "DNS", fmt.Sprintf("%s -> %s (%d ms)", siteHost, strings.Join(addrs, ", "), time.Since(start).Milliseconds()))
+	}
+
+	siteURL := "https://" + siteHost + "/"
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, siteURL, nil)
+	if err != nil {
+		report("FAIL", "HTTP", err.Error())
+	} else {
+		if proxy, err := http.ProxyFromEnvironment(req); err != nil {
+			report("WARN", "Proxy", fmt.Sprintf("invalid proxy settings: %v", err))
+		} else if proxy != nil {
+			report("OK", "Proxy", proxy.Redacted())
+		} else {
+			report("OK", "Proxy", "none")
+		}
+
+		start = time.Now()
+		resp, err := httpClient.Do(req)
+		switch {
+		case err != nil:
+			report("FAIL", "HTTP", fmt.Sprintf("HEAD %s: %v", siteURL, err))
+		case resp.StatusCode >= 400:
+			resp.Body.Close()
+			report("FAIL", "HTTP", fmt.Sprintf("HEAD %s: status %d (%d ms)", siteURL, resp.StatusCode, time.Since(start).Milliseconds()))
+		default:
+			resp.Body.Close()
+			report("OK", "HTTP", fmt.Sprintf("HEAD %s: status %d (%d ms)", siteURL, resp.StatusCode, time.Since(start).Milliseconds()))
+		}
+	}
+
+	configPath := expandHome(flags.Config)
+	switch {
+	case configPath == "":
+		report("WARN", "Config", "no config path available")
+	case isConfigURL(configPath) && cfgErr != nil:
+		report("FAIL", "Config", cfgErr.Error())
+	case isConfigURL(configPath):
+		report("OK", "Config", configPath+" (fetched, cached for "+remoteConfigTTL.String()+")")
+	case !fileExists(configPath):
+		report("WARN", "Config", configPath+" (not found)")
+	case cfgErr != nil:
+		report("FAIL", "Config", fmt.Sprintf("%s: %v", configPath, cfgErr))
+	default:
+		report("OK", "Config", configPath)
+	}
+	if configPath != "" && !isConfigURL(configPath) {
+		reportWritable(report, "Config dir", filepath.Dir(configPath))
+	}
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	cacheDir := expandHome(firstNonEmpty(flags.CacheDir, cfg.CacheDir, defaultCacheDir()))
+	if cacheDir == "" {
+		report("WARN", "Cache dir", "caching is disabled")
+	} else {
+		reportWritable(report, "Cache dir", cacheDir)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// reportWritable checks that dir exists and a file can be created in it.
+// A missing directory is only a warning since runs create it as needed.
+func reportWritable(report func(status, check, detail string), check, dir string) {
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		report("WARN", check, dir+" (does not exist yet)")
+		return
+	case err != nil:
+		report("FAIL", check, fmt.Sprintf("%s: %v", dir, err))
+		return
+	case !info.IsDir():
+		report("FAIL", check, dir+" is not a directory")
+		return
+	}
+	probe, err := os.CreateTemp(dir, ".kvartersmenyn-diagnose-*")
+	if err != nil {
+		report("FAIL", check, fmt.Sprintf("%s is not writable: %v", dir, err))
+		return
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	report("OK", check, dir+" (writable)")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runEditAreas lists the config's areas and lets the user remove, move and
+// relabel them interactively, then saves the config. It returns the exit
+// code.
+func runEditAreas(path string) int {
+	if isConfigURL(path) {
+		fmt.Fprintf(os.Stderr, "config %s is a URL and cannot be edited here\n", path)
+		return 1
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	areas := configAreas(cfg)
+	if skipped := len(cfg.Areas) - len(areas); len(cfg.Areas) > 0 && skipped > 0 {
+		fmt.Printf("Note: %d area(s) without a city are left out and will be dropped on save.\n", skipped)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	changed := false
+	for {
+		fmt.Println()
+		if len(areas) == 0 {
+			fmt.Println("No areas configured.")
+		}
+		for i, area := range areas {
+			fmt.Printf("%3d. %s\n", i+1, areaLabel(area))
+		}
+		fmt.Print("Command (r N remove, m N TO move, e N relabel, s save, q quit): ")
+		line, err := reader.ReadString('\n')
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if err != nil {
+				fmt.Println()
+				fmt.Println("Input ended, nothing saved.")
+				return 1
+			}
+			continue
+		}
+
+		switch cmd, args := strings.ToLower(fields[0]), fields[1:]; cmd {
+		case "r", "d", "remove", "delete":
+			i, ok := areaIndex(args, 0, len(areas))
+			if !ok || len(args) != 1 {
+				fmt.Println("Usage: r N")
+				continue
+			}
+			fmt.Printf("Removed %s.\n", areaLabel(areas[i]))
+			areas = append(areas[:i], areas[i+1:]...)
+			changed = true
+		case "m", "move":
+			from, okFrom := areaIndex(args, 0, len(areas))
+			to, okTo := areaIndex(args, 1, len(areas))
+			if !okFrom || !okTo || len(args) != 2 {
+				fmt.Println("Usage: m N TO")
+				continue
+			}
+			area := areas[from]
+			areas = append(areas[:from], areas[from+1:]...)
+			areas = append(areas[:to], append([]AreaConfig{area}, areas[to:]...)...)
+			changed = true
+		case "e", "edit":
+			i, ok := areaIndex(args, 0, len(areas))
+			if !ok || len(args) != 1 {
+				fmt.Println("Usage: e N")
+				continue
+			}
+			fmt.Printf("New city/area, area slug or kvartersmenyn URL for %s: ", areaLabel(areas[i]))
+			input, _ := reader.ReadString('\n')
+			area, ok := parseEditedArea(strings.TrimSpace(input), areas[i])
+			if !ok {
+				fmt.Println("Could not parse that; the area is unchanged.")
+				continue
+			}
+			areas[i] = area
+			changed = true
+		case "s", "save":
+			storeAreas(cfg, areas)
+			if err := saveConfig(path, cfg); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			fmt.Printf("Saved %d area(s).\n", len(areas))
+			return 0
+		case "q", "quit":
+			if changed {
+				fmt.Println("Changes discarded.")
+			}
+			return 0
+		default:
+			fmt.Printf("Unknown command %q.\n", cmd)
+		}
+	}
+}
+
+// areaIndex parses the 1-based position args[at] into an index below n.
+func areaIndex(args []string, at, n int) (int, bool) {
+	if at >= len(args) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(args[at])
+	if err != nil || i < 1 || i > n {
+		return 0, false
+	}
+	return i - 1, true
+}
+
+// parseEditedArea reads a replacement for area: a kvartersmenyn URL,
+// city/area or city:area, or a bare slug in area's city. The area's
+// headers are kept.
+func parseEditedArea(input string, area AreaConfig) (AreaConfig, bool) {
+	if input == "" {
+		return area, false
+	}
+	city, slug := area.City, input
+	switch {
+	case looksLikeURL(input):
+		var ok bool
+		if city, slug, ok = parseAreaURL(input); !ok {
+			return area, false
+		}
+	case strings.ContainsAny(input, "/:"):
+		city, slug, _ = strings.Cut(strings.Replace(input, ":", "/", 1), "/")
+	}
+	city, slug = strings.TrimSpace(city), strings.TrimSpace(slug)
+	if city == "" || strings.Contains(slug, "/") {
+		return area, false
+	}
+	return AreaConfig{City: city, Area: slug, Headers: area.Headers}, true
+}
+
+// storeAreas writes areas back into cfg the way promptAndSaveConfig does:
+// areas in the default city leave their city out.
+func storeAreas(cfg *Config, areas []AreaConfig) {
+	cfg.Area = ""
+	cfg.Areas = nil
+	for _, area := range areas {
+		if cfg.City != "" && area.City == cfg.City {
+			area.City = ""
+		}
+		cfg.Areas = append(cfg.Areas, area)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const emptyMarkerSuffix = ".empty"
+
+// emptyResultTTL is how long a "no lunches" marker is trusted. It is shorter
+// than the page cache since a menu may still be posted later.
+const emptyResultTTL = 30 * time.Minute
+
+// emptyMarkerPath puts the marker next to the cached page it belongs to.
+func emptyMarkerPath(dir, template string, area AreaConfig, day int) string {
+	name := strings.TrimSuffix(areaCacheName(template, area, day), ".html")
+	return filepath.Join(dir, name+emptyMarkerSuffix)
+}
+
+// knownEmpty reports whether a recent live fetch found no lunches for the
+// area and day, and when that was. The marker is ignored when the cache is
+// disabled or the normal TTL is shorter.
+func knownEmpty(opts Options, area AreaConfig, day int, now time.Time) (time.Time, bool) {
+	if opts.CacheDir == "" {
+		return time.Time{}, false
+	}
+	ttl := min(emptyResultTTL, effectiveCacheTTL(opts, day, now))
+	if ttl <= 0 {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(emptyMarkerPath(opts.CacheDir, opts.CacheNameTemplate, area, day))
+	if err != nil || now.Sub(info.ModTime()) > ttl {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// recordEmpty writes the marker after a live fetch without lunches and
+// removes it once one finds some.
+func recordEmpty(opts Options, area AreaConfig, day int, empty bool) {
+	if opts.CacheDir == "" {
+		return
+	}
+	path := emptyMarkerPath(opts.CacheDir, opts.CacheNameTemplate, area, day)
+	if !empty {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("could not remove empty marker (%s): %v", path, err)
+		}
+		return
+	}
+	if err := os.MkdirAll(opts.CacheDir, 0o755); err != nil {
+		return
+	}
+	if err := writeFileAtomic(path, nil, 0o644); err != nil {
+		log.Printf("could not write empty marker (%s): %v", path, err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const fingerprintSuffix = ".fingerprints.json"
+
+// fingerprintFile is the sidecar next to a cached page. Previous and Current
+// hold the menu fingerprints of the last two live fetches, keyed by name.
+type fingerprintFile struct {
+	Previous map[string]string `json:"previous"`
+	Current  map[string]string `json:"current"`
+}
+
+// fingerprintPath puts the sidecar next to the cached page it belongs to.
+func fingerprintPath(dir, template string, area AreaConfig, day int) string {
+	name := strings.TrimSuffix(areaCacheName(template, area, day), ".html")
+	return filepath.Join(dir, name+fingerprintSuffix)
+}
+
+// menuFingerprint hashes the normalized menu so whitespace changes do not count.
+func menuFingerprint(menu []string) string {
+	sum := sha256.Sum256([]byte(normalizeSpaces(strings.Join(menu, "\n"))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// markUpdated sets Updated on restaurants whose menu differs from the
+// previous live fetch. Only live fetches rotate the sidecar, so the markers
+// stay stable while the page is served from cache.
+func markUpdated(dir, template string, area AreaConfig, day int, live bool, restaurants []Restaurant) {
+	if dir == "" {
+		return
+	}
+	path := fingerprintPath(dir, template, area, day)
+
+	var sidecar fingerprintFile
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			log.Printf("ignoring unreadable fingerprints (%s): %v", path, err)
+			sidecar = fingerprintFile{}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("could not read fingerprints (%s): %v", path, err)
+	}
+
+	if live {
+		current := make(map[string]string, len(restaurants))
+		for _, r := range restaurants {
+			current[r.Name] = menuFingerprint(r.Menu)
+		}
+		sidecar.Previous, sidecar.Current = sidecar.Current, current
+		if data, err := json.Marshal(sidecar); err == nil {
+			if err := writeFileAtomic(path, data, 0o644); err != nil {
+				log.Printf("could not write fingerprints (%s): %v", path, err)
+			}
+		}
+	}
+
+	for i, r := range restaurants {
+		prev, ok := sidecar.Previous[r.Name]
+		restaurants[i].Updated = ok && prev != menuFingerprint(r.Menu)
+	}
+}
+
+// restaurantsFingerprint hashes every name and menu on a page, in order.
+func restaurantsFingerprint(restaurants []Restaurant) string {
+	var b strings.Builder
+	for _, r := range restaurants {
+		b.WriteString(r.Name)
+		b.WriteString("\x00")
+		b.WriteString(menuFingerprint(r.Menu))
+		b.WriteString("\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// staleMenuWarning flags today's page early in the day when it is empty or
+// identical to yesterday's cached page, which usually means the new menus
+// have not been posted yet.
+func staleMenuWarning(opts Options, area AreaConfig, restaurants []Restaurant, now time.Time) string {
+	const warning = "today's menu may not be posted yet"
+	if opts.Day != weekdayToDay(now.Weekday()) || now.Hour() >= opts.MenuPostedHour {
+		return ""
+	}
+	if len(restaurants) == 0 {
+		return warning
+	}
+	if opts.CacheDir == "" {
+		return ""
+	}
+
+	yesterday := (opts.Day+5)%7 + 1
+	file, err := os.Open(filepath.Join(opts.CacheDir, areaCacheName(opts.CacheNameTemplate, area, yesterday)))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || now.Sub(info.ModTime()) > 36*time.Hour {
+		return ""
+	}
+	previous, err := parseRestaurants(file)
+	if err != nil || len(previous) == 0 {
+		return ""
+	}
+	// Today's menus have been cleaned; clean yesterday's the same way.
+	for i := range previous {
+		previous[i].Menu = cleanMenuLines(previous[i].Menu, opts.MenuMinLine)
+	}
+	if restaurantsFingerprint(previous) == restaurantsFingerprint(restaurants) {
+		return warning
+	}
+	return ""
+}
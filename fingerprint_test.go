@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staleOptions returns options for today's page before the menus are
+// usually posted, with yesterday's page cached in a temporary directory.
+func staleOptions(t *testing.T, area AreaConfig, yesterdayPage string) (Options, time.Time) {
+	t.Helper()
+	now := time.Now()
+	opts := Options{
+		CacheDir:          t.TempDir(),
+		CacheNameTemplate: defaultCacheNameTemplate,
+		Day:               weekdayToDay(now.Weekday()),
+		MenuPostedHour:    24,
+		MenuMinLine:       defaultMenuMinLine,
+	}
+	yesterday := (opts.Day+5)%7 + 1
+	path := filepath.Join(opts.CacheDir, areaCacheName(opts.CacheNameTemplate, area, yesterday))
+	if err := os.WriteFile(path, []byte(yesterdayPage), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return opts, now
+}
+
+func TestStaleMenuWarningComparesCleanedMenus(t *testing.T) {
+	area := AreaConfig{City: "goteborg", Area: "garda_161"}
+	page := listingRow("Kycklinggryta<br>*<br>Fiskgratäng")
+	opts, now := staleOptions(t, area, page)
+
+	today, err := parseRestaurants(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range today {
+		today[i].Menu = cleanMenuLines(today[i].Menu, opts.MenuMinLine)
+	}
+	if got := staleMenuWarning(opts, area, today, now); got == "" {
+		t.Error("no warning for a page identical to yesterday's")
+	}
+}
+
+// twoRestaurantPage lists an open restaurant and one that is closed today.
+const twoRestaurantPage = `<html><body>
+<div class="row t_lunch"><div class="name"><h5 class="t_lunch"><a href="/rest/1">Koka Bistro</a></h5></div>
+<div class="price-rl"><span class="price">129:-</span></div>
+<div class="rest-menu"><p class="t_lunch">Köttbullar med potatis<br>Vegetarisk lasagne</p></div>
+<div class="divider"><p>ADRESS: Kungsgatan 12</p></div></div>
+<div class="row t_lunch"><div class="name"><h5 class="t_lunch"><a href="/rest/2">Gårda Kök</a></h5></div>
+<div class="price-rl"><span class="price"></span></div>
+<div class="rest-menu"><p class="t_lunch">Stängt</p></div>
+<div class="divider"><p>ADRESS: Gårdavägen 1</p></div></div>
+</body></html>`
+
+// loadStale loads today's page from the cache with yesterday's identical
+// page next to it and returns the stale-menu warning.
+func loadStale(t *testing.T, configure func(*Options)) string {
+	t.Helper()
+	area := AreaConfig{City: "goteborg", Area: "garda_161"}
+	opts, _ := staleOptions(t, area, twoRestaurantPage)
+	opts.CacheTTL = time.Hour
+	configure(&opts)
+	path := filepath.Join(opts.CacheDir, areaCacheName(opts.CacheNameTemplate, area, opts.Day))
+	if err := os.WriteFile(path, []byte(twoRestaurantPage), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, info, err := loadRestaurants(context.Background(), opts, area)
+	if err != nil {
+		t.Fatalf("loadRestaurants: %v", err)
+	}
+	return info.Warning
+}
+
+func TestStaleMenuWarningIgnoresClosedFilter(t *testing.T) {
+	if loadStale(t, func(*Options) {}) == "" {
+		t.Error("no warning when a closed restaurant was filtered out")
+	}
+}
+
+func TestStaleMenuWarningIgnoresBlocklist(t *testing.T) {
+	warning := loadStale(t, func(opts *Options) {
+		opts.ShowClosed = true
+		opts.Blocklist = []string{"Koka Bistro"}
+	})
+	if warning == "" {
+		t.Error("no warning when a blocklisted restaurant was filtered out")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const freshnessSuffix = ".freshness.json"
+
+// freshnessFile is the sidecar next to a cached page recording the
+// server's freshness hint from the live fetch, for --respect-cache-control.
+type freshnessFile struct {
+	CacheControl string    `json:"cache_control,omitempty"`
+	Expires      string    `json:"expires,omitempty"`
+	FreshUntil   time.Time `json:"fresh_until"`
+}
+
+// freshnessPath puts the sidecar next to the cached page it belongs to.
+func freshnessPath(dir, cacheName string) string {
+	return filepath.Join(dir, strings.TrimSuffix(cacheName, ".html")+freshnessSuffix)
+}
+
+// serverFreshness reads how long the response may be reused: max-age from
+// Cache-Control (less any Age), else Expires. no-cache and no-store make it
+// stale at once. It reports false when the server gives no hint.
+func serverFreshness(header http.Header, now time.Time) (freshnessFile, bool) {
+	fresh := freshnessFile{CacheControl: header.Get("Cache-Control"), Expires: header.Get("Expires")}
+	for _, directive := range strings.Split(fresh.CacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-cache", "no-store":
+			fresh.FreshUntil = now
+			return fresh, true
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				continue
+			}
+			if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+				seconds = max(seconds-age, 0)
+			}
+			fresh.FreshUntil = now.Add(time.Duration(seconds) * time.Second)
+			return fresh, true
+		}
+	}
+	if fresh.Expires != "" {
+		expires, err := http.ParseTime(fresh.Expires)
+		if err != nil {
+			// An invalid Expires, such as "0", means already expired.
+			expires = now
+		}
+		fresh.FreshUntil = expires
+		return fresh, true
+	}
+	return freshnessFile{}, false
+}
+
+// saveFreshness records the server's hint for a page just cached, or
+// removes an older one when the server no longer sends any.
+func saveFreshness(dir, cacheName string, header http.Header, now time.Time) {
+	if dir == "" {
+		return
+	}
+	path := freshnessPath(dir, cacheName)
+	fresh, ok := serverFreshness(header, now)
+	if !ok {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("could not remove freshness (%s): %v", path, err)
+		}
+		return
+	}
+	data, err := json.Marshal(fresh)
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		log.Printf("could not write freshness (%s): %v", path, err)
+	}
+}
+
+// serverCacheTTL turns the recorded hint for a cached page into a TTL for
+// tryCache, which measures age from the page's modification time. It
+// reports false when there is no hint, so the configured TTL applies.
+func serverCacheTTL(dir, cacheName string) (time.Duration, bool) {
+	if dir == "" {
+		return 0, false
+	}
+	data, err := os.ReadFile(freshnessPath(dir, cacheName))
+	if err != nil {
+		return 0, false
+	}
+	var fresh freshnessFile
+	if err := json.Unmarshal(data, &fresh); err != nil || fresh.FreshUntil.IsZero() {
+		return 0, false
+	}
+	info, err := os.Stat(filepath.Join(dir, cacheName))
+	if err != nil {
+		return 0, false
+	}
+	return fresh.FreshUntil.Sub(info.ModTime()), true
+}
@@ -6,10 +6,9 @@ require (
 	github.com/PuerkitoBio/goquery v1.9.2
 	github.com/lithammer/fuzzysearch v1.1.5
 	golang.org/x/net v0.24.0
+	golang.org/x/text v0.14.0
+	golang.org/x/time v0.5.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/andybalholm/cascadia v1.3.2 // indirect
-	golang.org/x/text v0.14.0 // indirect
-)
+require github.com/andybalholm/cascadia v1.3.2 // indirect
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// hookTimeout bounds one run of an --on-success or --on-empty command.
+const hookTimeout = 30 * time.Second
+
+// runHook runs command through the shell (see hookShell) once a run is done, with the
+// run's counts in the environment, and logs how it exited. Its output goes
+// to stderr so stdout keeps only results.
+func runHook(name, command string, matches, areas, failed int) {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	shell, args := hookShell(command)
+	cmd := exec.CommandContext(ctx, shell, args...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("KVARTERSMENYN_MATCHES=%d", matches),
+		fmt.Sprintf("KVARTERSMENYN_AREAS=%d", areas),
+		fmt.Sprintf("KVARTERSMENYN_FAILED=%d", failed),
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		log.Printf("%s hook timed out after %s: %s", name, hookTimeout, command)
+	case errors.As(err, &exitErr):
+		log.Printf("%s hook exited with status %d: %s", name, exitErr.ExitCode(), command)
+	case err != nil:
+		log.Printf("could not run %s hook: %v", name, err)
+	default:
+		slog.Info(name+" hook exited with status 0", "command", command, "duration_ms", time.Since(started).Milliseconds())
+	}
+}
+
+// hookShell returns the shell that runs a hook command: sh -c, or cmd /C on
+// Windows, where there is no sh.
+func hookShell(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
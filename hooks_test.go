@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunHookEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.txt")
+	command := `echo "$KVARTERSMENYN_MATCHES $KVARTERSMENYN_AREAS $KVARTERSMENYN_FAILED" > "` + out + `"`
+	if runtime.GOOS == "windows" {
+		command = `echo %KVARTERSMENYN_MATCHES% %KVARTERSMENYN_AREAS% %KVARTERSMENYN_FAILED%> "` + out + `"`
+	}
+	runHook("on-success", command, 3, 2, 1)
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "3 2 1" {
+		t.Errorf("hook saw %q, want %q", got, "3 2 1")
+	}
+}
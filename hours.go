@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hoursPattern finds a time range like "11-14", "kl 11.00–14.30" or
+// "11:00 - 13:30".
+var hoursPattern = regexp.MustCompile(`\b([01]?\d|2[0-3])(?:[.:]([0-5]\d))?\s*[-–—]\s*([01]?\d|2[0-3])(?:[.:]([0-5]\d))?\b`)
+
+// hoursKeywords mark a menu line as talking about serving hours, so dish
+// names with numbers are not mistaken for times.
+var hoursKeywords = []string{"lunch", "kl", "serveras", "öppet", "open"}
+
+// parseLunchHours looks for serving hours in the menu and returns them as
+// "11:00–14:00", or "" when none are listed.
+func parseLunchHours(lines []string) string {
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if !containsAny(lower, hoursKeywords) {
+			continue
+		}
+		m := hoursPattern.FindStringSubmatch(lower)
+		if m == nil {
+			continue
+		}
+		start := clockMinutes(m[1], m[2])
+		end := clockMinutes(m[3], m[4])
+		if start >= end || start < 6*60 || end > 23*60 {
+			continue
+		}
+		return fmt.Sprintf("%02d:%02d–%02d:%02d", start/60, start%60, end/60, end%60)
+	}
+	return ""
+}
+
+func clockMinutes(hour, minute string) int {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	return h*60 + m
+}
+
+func containsAny(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// lunchWindow returns the serving hours as minutes after midnight.
+func lunchWindow(r Restaurant) (int, int, bool) {
+	start, end, ok := strings.Cut(r.Hours, "–")
+	if !ok {
+		return 0, 0, false
+	}
+	startHour, startMinute, _ := strings.Cut(start, ":")
+	endHour, endMinute, _ := strings.Cut(end, ":")
+	return clockMinutes(startHour, startMinute), clockMinutes(endHour, endMinute), true
+}
+
+// servingMinutesLeft is how long a restaurant still serves lunch at now:
+// the whole window before it opens, 0 once it has closed and -1 when its
+// hours are unknown.
+func servingMinutesLeft(r Restaurant, now time.Time) int {
+	start, end, ok := lunchWindow(r)
+	if !ok {
+		return -1
+	}
+	current := now.Hour()*60 + now.Minute()
+	switch {
+	case current >= end:
+		return 0
+	case current < start:
+		return end - start
+	default:
+		return end - current
+	}
+}
+
+// sortByNearNow puts restaurants still serving first, longest remaining
+// window first, then those with unknown hours and finally those closed for
+// the day.
+func sortByNearNow(restaurants []Restaurant, now time.Time) {
+	rank := func(r Restaurant) (int, int) {
+		switch left := servingMinutesLeft(r, now); {
+		case left > 0:
+			return 0, -left
+		case left < 0:
+			return 1, 0
+		default:
+			return 2, 0
+		}
+	}
+	sort.SliceStable(restaurants, func(i, j int) bool {
+		gi, li := rank(restaurants[i])
+		gj, lj := rank(restaurants[j])
+		if gi != gj {
+			return gi < gj
+		}
+		return li < lj
+	})
+}
+
+// filterOpenNow drops restaurants whose lunch has ended at now. Restaurants
+// without listed hours are kept.
+func filterOpenNow(restaurants []Restaurant, now time.Time) []Restaurant {
+	var open []Restaurant
+	for _, r := range restaurants {
+		if servingMinutesLeft(r, now) != 0 {
+			open = append(open, r)
+		}
+	}
+	return open
+}
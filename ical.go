@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// icalLineLimit is the maximum octets per content line before folding
+// (RFC 5545, section 3.1).
+const icalLineLimit = 75
+
+// writeICal prints one all-day VEVENT per day, dated within the week of now,
+// listing the restaurants found in every area for that day.
+func writeICal(w io.Writer, results []areaResult, now time.Time) error {
+	byDay := map[int][]areaResult{}
+	var days []int
+	for _, result := range results {
+		if _, ok := byDay[result.Day]; !ok {
+			days = append(days, result.Day)
+		}
+		byDay[result.Day] = append(byDay[result.Day], result)
+	}
+	sort.Ints(days)
+
+	monday := now.AddDate(0, 0, 1-weekdayToDay(now.Weekday()))
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	bw := bufio.NewWriter(w)
+	line := func(text string) {
+		bw.WriteString(foldICalLine(text))
+		bw.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//kvartersmenyn-cli//lunch menus//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, day := range days {
+		date := monday.AddDate(0, 0, day-1)
+		var description []string
+		for _, result := range byDay[day] {
+			if len(byDay[day]) > 1 {
+				description = append(description, areaLabel(result.Area)+":")
+			}
+			if len(result.Restaurants) == 0 {
+				description = append(description, "No matches.")
+			}
+			for _, r := range result.Restaurants {
+				entry := r.Name
+				if price := formatPrice(r, priceFormatKr); price != "" {
+					entry += " — " + price
+				}
+				description = append(description, entry)
+			}
+		}
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s-%s@kvartersmenyn-cli", date.Format("20060102"), icalUIDPart(byDay[day])))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICalText("Lunch options"))
+		line("DESCRIPTION:" + escapeICalText(strings.Join(description, "\n")))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// icalUIDPart keeps UIDs stable per set of areas so re-imports update events.
+func icalUIDPart(results []areaResult) string {
+	labels := make([]string, len(results))
+	for i, result := range results {
+		labels[i] = strings.ReplaceAll(areaLabel(result.Area), "/", "-")
+	}
+	return strings.Join(labels, "+")
+}
+
+// escapeICalText escapes a TEXT value per RFC 5545, section 3.3.11.
+func escapeICalText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// foldICalLine splits long lines with CRLF plus a space, never inside a
+// multi-byte character.
+func foldICalLine(text string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range text {
+		size := len(string(r))
+		if width+size > icalLineLimit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
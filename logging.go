@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// maxLogFileSize is the size at which --log-file is rotated to path.1 when
+// a run starts, so scheduled runs do not grow it forever.
+const maxLogFileSize = 10 << 20
+
+// setupLogging configures operational logs on stderr, or appended to
+// logFile when set. Text keeps the standard log output and hides debug
+// events unless verbose is set; JSON turns every log line, including those
+// from the log package, into a JSON object and includes the fetch and cache
+// events.
+func setupLogging(format string, verbose bool, logFile string) error {
+	var w io.Writer = os.Stderr
+	if logFile != "" {
+		file, err := openLogFile(expandHome(logFile))
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", logFormatText:
+		log.SetOutput(w)
+		if verbose {
+			handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+			slog.SetDefault(slog.New(handler))
+		}
+		return nil
+	case logFormatJSON:
+		handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+		slog.SetDefault(slog.New(handler))
+		return nil
+	default:
+		return fmt.Errorf("invalid --log-format %q (use text or json)", format)
+	}
+}
+
+// openLogFile opens path for appending, first moving it to path.1 when it
+// has reached maxLogFileSize. Only one old file is kept.
+func openLogFile(path string) (*os.File, error) {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.Size() >= maxLogFileSize:
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, fmt.Errorf("could not rotate log file: %w", err)
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("could not open log file: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log file: %w", err)
+	}
+	return file, nil
+}
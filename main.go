@@ -4,34 +4,119 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/lithammer/fuzzysearch/fuzzy"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/time/rate"
 )
 
 type Flags struct {
-	City     string
-	Areas    areaList
-	Name     string
-	Search   string
-	Menu     string
-	Day      string
-	CacheDir string
-	CacheTTL string
-	Config   string
-	Help     bool
-	InitCfg  bool
-	Version  bool
+	City      string
+	Areas     areaList
+	Name      string
+	Search    string
+	Menu      string
+	Day       string
+	CacheDir  string
+	CacheTTL  string
+	Config    string
+	Help      bool
+	InitCfg   bool
+	EditCfg   bool
+	EditAreas bool
+	PrintCfg  bool
+	Version   bool
+	SelfTest  bool
+	Diagnose  bool
+	Schema    bool
+
+	PriceCurrency string
+	MaxPrice      string
+	PriceFormat   string
+	SaveHTML      string
+	Buckets       bool
+	Headers       headerList
+	Explain       bool
+	FailFast      bool
+	Continue      bool
+	Wrap          string
+	Postcode      string
+	MenuLines     int
+	Format        string
+
+	HighlightUpdated    bool
+	Discover            string
+	MergeDays           string
+	LogFormat           string
+	ShowClosed          bool
+	Repeat              bool
+	ExpandSubareas      bool
+	Compare             bool
+	AreasMatch          string
+	RateLimit           string
+	NameExact           bool
+	Week                bool
+	Sort                string
+	Verbose             bool
+	CacheNameTmpl       string
+	Missing             string
+	SortAreas           string
+	Color               string
+	Watch               string
+	ExitOnChange        bool
+	Translate           bool
+	NearNow             bool
+	OpenNow             bool
+	LimitPerCity        int
+	PostProcess         string
+	Tight               bool
+	Stdin               bool
+	Yes                 bool
+	DishPrice           bool
+	JSONStream          bool
+	Address             string
+	Rotate              bool
+	ShowURL             bool
+	WatchDiff           bool
+	OutputDir           string
+	Category            string
+	InferCategory       bool
+	CacheHistoryList    bool
+	ChangedOnly         bool
+	ExcludeAreas        areaList
+	FromJSON            string
+	SummaryJSON         bool
+	CaseSensitive       bool
+	StrictParse         bool
+	LogFile             string
+	OnSuccess           string
+	OnEmpty             string
+	MinResults          int
+	MetricsFile         string
+	Separator           string
+	TopWords            int
+	CompareDays         bool
+	RespectCacheControl bool
+	HasLink             bool
 }
 
 // Options are the merged result of flags + config + defaults.
@@ -43,12 +128,111 @@ type Options struct {
 	Day      int
 	CacheDir string
 	CacheTTL time.Duration
+	// CacheUntil is the time of day for a cache_ttl of until:HH:MM, as an
+	// offset from midnight.
+	CacheUntil time.Duration
+	// CacheHistory is how many timestamped snapshots of each cached page
+	// are kept; zero keeps none.
+	CacheHistory int
+
+	PriceCurrency string
+	EURRate       float64
+	MaxPrice      float64
+	PriceFormat   string
+	SaveHTML      string
+	Buckets       bool
+	PriceBuckets  []float64
+	Headers       map[string]string
+	Explain       bool
+	FailFast      bool
+	Wrap          string
+	Separator     string
+	MenuLines     int
+	Format        string
+
+	HighlightUpdated bool
+	MenuPostedHour   int
+	MergeDays        []int
+	ShowClosed       bool
+	Repeat           bool
+	ExpandSubareas   bool
+	Compare          bool
+	MenuMinLine      int
+	NameExact        bool
+	Week             bool
+	Sort             string
+	SortAreas        string
+	OpenNow          bool
+	LimitPerCity     int
+	PostProcess      string
+	Tight            bool
+	MaxAreas         int
+	Yes              bool
+	DishPrice        bool
+	JSONStream       bool
+	Address          string
+	Rotate           bool
+	ShowURL          bool
+	OutputDir        string
+	Category         string
+	InferCategory    bool
+	ChangedOnly      bool
+	// FromJSON is the --from-json file and Archive the results read from
+	// it, which replace fetching.
+	FromJSON string
+	Archive  []areaResult
+	// SummaryJSON adds run statistics to JSON output.
+	SummaryJSON   bool
+	CaseSensitive bool
+	StrictParse   bool
+	// OnSuccess and OnEmpty are shell commands run after a run with and
+	// without matches.
+	OnSuccess string
+	OnEmpty   string
+	// MinResults fails the run when fewer restaurants matched in total.
+	MinResults int
+	// MetricsFile receives Prometheus textfile metrics after the run.
+	MetricsFile string
+	// TopWords replaces the listing with the N most frequent menu words;
+	// StopWords from config are left out on top of defaultStopWords.
+	TopWords  int
+	StopWords []string
+	// CompareDays pivots a --week run into a day-by-day table per
+	// restaurant.
+	CompareDays bool
+	// RespectCacheControl lets the server's Cache-Control or Expires
+	// replace the cache TTL, see serverCacheTTL.
+	RespectCacheControl bool
+	// Watch is the --watch interval; zero runs once.
+	Watch        time.Duration
+	WatchDiff    bool
+	ExitOnChange bool
+	// CacheNameTemplate names cached area pages, see areaCacheName.
+	CacheNameTemplate string
+	// Translator is set by --translate; nil leaves menus untranslated.
+	Translator *translator
+	// Missing lists fields that must be empty, see filterMissing.
+	Missing []string
+	// HasLink keeps only restaurants with a usable link, see filterHasLink.
+	HasLink bool
+	// Blocklist names are always filtered out, see filterBlocklist.
+	Blocklist []string
+	// Limiter throttles live fetches; nil means unlimited.
+	Limiter *rate.Limiter
 }
 
 type SourceInfo struct {
+	// Label names the area without the day, which is added where it is
+	// shown; merged days are part of it.
 	Label        string
 	Source       string
 	CacheUpdated time.Time
+	// Warning is shown in the header, e.g. when today's menu looks unposted.
+	Warning string
+	// URL is the page the data comes from, before any redirects.
+	URL string
+	// FinalURL is the page a live fetch ended up on when it was redirected.
+	FinalURL string
 }
 
 // areaList lets --area be repeated and/or comma-separated.
@@ -68,54 +252,254 @@ func (a *areaList) Set(value string) error {
 	return nil
 }
 
+// headerList collects repeated --header "Key: Value" flags.
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Set(value string) error {
+	if _, _, err := parseHeader(value); err != nil {
+		return err
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+func parseHeader(raw string) (string, string, error) {
+	key, value, ok := strings.Cut(raw, ":")
+	key = strings.TrimSpace(key)
+	if !ok || !validHeaderKey(key) {
+		return "", "", fmt.Errorf("invalid header %q (use \"Key: Value\")", raw)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+func validHeaderKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, " \t:")
+}
+
 var version = "dev"
 
 func main() {
+	// A leading flag (or no args) keeps the flat invocation working as "list".
+	cmd, args := "list", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list":
+		runList(args)
+	case "config":
+		runConfig(args)
+	case "cache":
+		runCache(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q (use list, config or cache)\n", cmd)
+		os.Exit(2)
+	}
+}
+
+func runList(args []string) {
 	flags := Flags{}
-	flag.StringVar(&flags.City, "city", "", "City segment used in the kvartersmenyn URL (can be set in config)")
-	flag.StringVar(&flags.City, "c", "", "Short for --city")
-	flag.Var(&flags.Areas, "area", "Area slug from kvartersmenyn, e.g. garda_161 (can be repeated or comma-separated)")
-	flag.Var(&flags.Areas, "a", "Short for --area")
-	flag.StringVar(&flags.Name, "name", "", "Filter by restaurant name (fuzzy, case-insensitive)")
-	flag.StringVar(&flags.Name, "n", "", "Short for --name")
-	flag.StringVar(&flags.Menu, "menu", "", "Filter by menu text (fuzzy, case-insensitive)")
-	flag.StringVar(&flags.Menu, "m", "", "Short for --menu")
-	flag.StringVar(&flags.Search, "search", "", "Filter both name and menu (fuzzy, case-insensitive)")
-	flag.StringVar(&flags.Search, "s", "", "Short for --search")
-	flag.StringVar(&flags.Day, "day", "", "Day of week to fetch (mon, tue, wed, thu, fri, sat, sun or 1-7)")
-	flag.StringVar(&flags.Day, "d", "", "Short for --day")
-	flag.StringVar(&flags.CacheDir, "cache-dir", "", "Directory for cached HTML (empty to disable, can be set in config)")
-	flag.StringVar(&flags.CacheDir, "C", "", "Short for --cache-dir")
-	flag.StringVar(&flags.CacheTTL, "cache-ttl", "", "How long to reuse cached HTML (e.g. 6h, 2h). Overwrites config/default when set.")
-	flag.StringVar(&flags.CacheTTL, "t", "", "Short for --cache-ttl")
-	flag.StringVar(&flags.Config, "config", defaultConfigPath(), "Path to YAML config (city, area, cache)")
-	flag.StringVar(&flags.Config, "f", defaultConfigPath(), "Short for --config")
-	flag.BoolVar(&flags.Help, "help", false, "Show help")
-	flag.BoolVar(&flags.Help, "h", false, "Short for --help")
-	flag.BoolVar(&flags.InitCfg, "init-config", false, "Run the interactive config setup and exit")
-	flag.BoolVar(&flags.InitCfg, "i", false, "Short for --init-config")
-	flag.BoolVar(&flags.Version, "version", false, "Show version and exit")
-	flag.Usage = func() {
-		out := flag.CommandLine.Output()
-		fmt.Fprintf(out, "Usage: %s [options]\n\n", os.Args[0])
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.StringVar(&flags.City, "city", "", "City segment used in the kvartersmenyn URL (can be set in config)")
+	fs.StringVar(&flags.City, "c", "", "Short for --city")
+	fs.Var(&flags.Areas, "area", "Area slug from kvartersmenyn, e.g. garda_161 (can be repeated or comma-separated)")
+	fs.Var(&flags.Areas, "a", "Short for --area")
+	fs.StringVar(&flags.Name, "name", "", "Filter by restaurant name (fuzzy, case-insensitive)")
+	fs.StringVar(&flags.Name, "n", "", "Short for --name")
+	fs.StringVar(&flags.Category, "category", "", "Only show restaurants of this cuisine or category, e.g. italian")
+	fs.BoolVar(&flags.InferCategory, "infer-category", false, "Guess each restaurant's category from its name and menu (implied by --category)")
+	fs.StringVar(&flags.Address, "address", "", "Filter by address, e.g. a street name (fuzzy, case-insensitive)")
+	fs.BoolVar(&flags.NameExact, "name-exact", false, "Match --name exactly (case-insensitive) instead of fuzzy")
+	fs.BoolVar(&flags.CaseSensitive, "case-sensitive", false, "Match --name, --menu and --search as verbatim, case-sensitive substrings")
+	fs.StringVar(&flags.Menu, "menu", "", "Filter by menu text (fuzzy, case-insensitive)")
+	fs.StringVar(&flags.Menu, "m", "", "Short for --menu")
+	fs.StringVar(&flags.Search, "search", "", "Filter both name and menu (fuzzy, case-insensitive)")
+	fs.StringVar(&flags.Search, "s", "", "Short for --search")
+	fs.StringVar(&flags.Day, "day", "", "Day to fetch (mon-sun, mån-sön, 1-7, today, tomorrow or \"next monday\")")
+	fs.StringVar(&flags.Day, "d", "", "Short for --day")
+	fs.StringVar(&flags.CacheDir, "cache-dir", "", "Directory for cached HTML (empty to disable, can be set in config)")
+	fs.StringVar(&flags.CacheDir, "C", "", "Short for --cache-dir")
+	fs.StringVar(&flags.CacheNameTmpl, "cache-name-template", "", "Cache file name with {city}, {area} and {day} (default "+defaultCacheNameTemplate+")")
+	fs.StringVar(&flags.CacheTTL, "cache-ttl", "", "How long to reuse cached HTML (e.g. 6h, 2h, auto or until:10:00). Overwrites config/default when set.")
+	fs.StringVar(&flags.CacheTTL, "t", "", "Short for --cache-ttl")
+	fs.BoolVar(&flags.RespectCacheControl, "respect-cache-control", false, "Let the server's Cache-Control/Expires decide how long a page is reused")
+	fs.BoolVar(&flags.CacheHistoryList, "cache-history-list", false, "List the kept cache snapshots of the selected areas and day and exit")
+	fs.StringVar(&flags.Config, "config", defaultConfigPath(), "Path to YAML config (city, area, cache)")
+	fs.StringVar(&flags.Config, "f", defaultConfigPath(), "Short for --config")
+	fs.StringVar(&flags.PriceCurrency, "price-currency", "", "Currency assumed for prices without a marker (SEK or EUR, can be set in config)")
+	fs.StringVar(&flags.PriceFormat, "price-format", "", "How prices are shown: raw (default), kr or symbol")
+	fs.StringVar(&flags.MaxPrice, "max-price", "", "Only show restaurants priced at or below this amount in SEK")
+	fs.BoolVar(&flags.DishPrice, "dish-price", false, "Apply --max-price to dishes with their own price in the menu")
+	fs.BoolVar(&flags.HasLink, "has-link", false, "Only show restaurants that link to their own page")
+	fs.StringVar(&flags.Missing, "missing", "", "Only show restaurants missing a field: name, price, address, phone, link or menu")
+	fs.StringVar(&flags.Sort, "sort", "", "Order restaurants: site (default) or near-now")
+	fs.BoolVar(&flags.NearNow, "near-now", false, "Order by how much of the lunch window is left (same as --sort near-now)")
+	fs.BoolVar(&flags.OpenNow, "open-now", false, "Hide restaurants whose listed lunch hours have ended")
+	fs.IntVar(&flags.LimitPerCity, "limit-per-city", 0, "Show at most N restaurants per city across its areas (0 = all)")
+	fs.BoolVar(&flags.Rotate, "rotate", false, "Pick one matching restaurant for the day, varying it from recent days")
+	fs.StringVar(&flags.SortAreas, "sort-areas", "", "Order areas: config (default) or count (most matches first)")
+	fs.StringVar(&flags.AreasMatch, "areas-match", "", "Only use areas whose city/area label matches a glob or substring")
+	fs.Var(&flags.ExcludeAreas, "exclude-area", "Skip an area by slug or city/area label (can be repeated or comma-separated)")
+	fs.StringVar(&flags.FromJSON, "from-json", "", "Re-render a file written by --format json instead of fetching")
+	fs.BoolVar(&flags.Stdin, "stdin", false, "Read city/area targets or URLs from stdin, one per line, instead of config")
+	fs.BoolVar(&flags.Yes, "yes", false, "Fetch more than max_areas areas without asking")
+	fs.StringVar(&flags.Postcode, "postcode", "", "Postcode to resolve to area slug(s) instead of --area")
+	fs.BoolVar(&flags.HighlightUpdated, "highlight-updated", false, "Mark restaurants whose menu changed since the previous fetch")
+	fs.BoolVar(&flags.ChangedOnly, "changed-only", false, "Only show restaurants whose menu changed since the previous cache snapshot")
+	fs.StringVar(&flags.OutputDir, "output-dir", "", "Write each area and day to its own file in this directory")
+	fs.BoolVar(&flags.SummaryJSON, "summary-json", false, "Add run statistics (cache hits, prices, timings) to JSON output")
+	fs.BoolVar(&flags.JSONStream, "json-stream", false, "Print one JSON object per area and day as soon as it is done (NDJSON)")
+	fs.StringVar(&flags.Format, "format", "", "Output format: "+strings.Join(outputFormats, ", ")+" (can be set in config)")
+	fs.IntVar(&flags.MenuLines, "menu-lines", 0, "Show at most N menu lines per restaurant (0 shows all)")
+	fs.StringVar(&flags.PostProcess, "post-process", "", "Pipe each area's restaurants as JSON through this command before printing")
+	fs.BoolVar(&flags.Translate, "translate", false, "Show each menu line translated by translate_cmd from config")
+	fs.BoolVar(&flags.ShowURL, "show-url", false, "Show the page URL each area was fetched from in the header")
+	fs.BoolVar(&flags.Tight, "tight", false, "Omit the blank line between restaurants")
+	fs.StringVar(&flags.Color, "color", "", "Color output: auto (default), always or never")
+	fs.StringVar(&flags.Wrap, "wrap", "", "How to wrap long lines: word (default), off or char")
+	fs.StringVar(&flags.Separator, "separator", "", "Between areas in text output: rule (default), blank or none")
+	fs.StringVar(&flags.RateLimit, "rate-limit", "", "Max live requests, e.g. 2/s or 30/m (cache hits are not limited)")
+	fs.BoolVar(&flags.Verbose, "verbose", false, "Log fetches, cache use and removed entries to stderr")
+	fs.BoolVar(&flags.Verbose, "v", false, "Short for --verbose")
+	fs.StringVar(&flags.LogFormat, "log-format", "", "Format of operational logs on stderr: text (default) or json")
+	fs.StringVar(&flags.LogFile, "log-file", "", "Append operational logs to this file instead of stderr")
+	fs.StringVar(&flags.OnSuccess, "on-success", "", "Shell command to run after a run with matches")
+	fs.StringVar(&flags.OnEmpty, "on-empty", "", "Shell command to run after a run without matches")
+	fs.IntVar(&flags.TopWords, "top-words", 0, "Print the N most frequent menu words across matched restaurants instead of the menus")
+	fs.StringVar(&flags.MetricsFile, "metrics-file", "", "Write Prometheus textfile metrics for the run to this file")
+	fs.IntVar(&flags.MinResults, "min-results", 0, "Exit 1 when fewer than N restaurants matched across all areas (0 = off)")
+	fs.BoolVar(&flags.StrictParse, "strict-parse", false, "Fail an area when a live page parses suspiciously (no restaurants, many blocks without name or price)")
+	fs.BoolVar(&flags.FailFast, "fail-fast", false, "Stop at the first area that fails to fetch or parse (default when stdout is not a terminal)")
+	fs.BoolVar(&flags.Continue, "continue", false, "Process all areas and report failures at the end (default in a terminal)")
+	fs.StringVar(&flags.Watch, "watch", "", "Fetch again every interval (e.g. 5m) and print the results each time")
+	fs.BoolVar(&flags.WatchDiff, "watch-diff", false, "With --watch, only print what changed since the previous cycle")
+	fs.BoolVar(&flags.ExitOnChange, "exit-on-change", false, "With --watch, exit 0 the first time the results change")
+	fs.BoolVar(&flags.Repeat, "repeat", false, "Fetch once, then prompt for filter queries until q")
+	fs.BoolVar(&flags.Explain, "explain", false, "Show why each restaurant matched the filters")
+	fs.BoolVar(&flags.ExpandSubareas, "expand-subareas", false, "Follow sub-areas when an area page lists none but links to children")
+	fs.BoolVar(&flags.ShowClosed, "show-closed", false, "Keep closed/placeholder entries (hidden by default)")
+	fs.BoolVar(&flags.Week, "week", false, "Fetch every weekday (mon-fri) of the current week")
+	fs.BoolVar(&flags.CompareDays, "compare-days", false, "With --week and --name, show each matched restaurant's week as one table")
+	fs.StringVar(&flags.MergeDays, "merge-days", "", "Fetch a range of days (e.g. mon-fri) and merge them into one deduped list")
+	fs.BoolVar(&flags.Compare, "compare", false, "Show exactly two areas side by side")
+	fs.BoolVar(&flags.Buckets, "buckets", false, "Group results into price ranges (boundaries can be set in config)")
+	fs.Var(&flags.Headers, "header", "Extra request header as \"Key: Value\" (can be repeated, can be set in config)")
+	fs.StringVar(&flags.SaveHTML, "save-html", "", "Also write each area's HTML to this directory (for bug reports)")
+	fs.BoolVar(&flags.Help, "help", false, "Show help")
+	fs.BoolVar(&flags.Help, "h", false, "Short for --help")
+	fs.BoolVar(&flags.InitCfg, "init-config", false, "Run the interactive config setup and exit")
+	fs.BoolVar(&flags.InitCfg, "i", false, "Short for --init-config")
+	fs.BoolVar(&flags.EditCfg, "edit-config", false, "Open the config in $EDITOR, then validate it")
+	fs.BoolVar(&flags.EditAreas, "edit-areas", false, "Remove, reorder or relabel the config's areas interactively")
+	fs.BoolVar(&flags.PrintCfg, "print-config", false, "Print the merged flags, config and defaults as YAML and exit")
+	fs.BoolVar(&flags.Version, "version", false, "Show version and exit")
+	fs.StringVar(&flags.Discover, "discover", "", "List the areas (name and slug) for a city and exit")
+	fs.BoolVar(&flags.SelfTest, "self-test", false, "Fetch a known area live and check that the scraper still works")
+	fs.BoolVar(&flags.Diagnose, "diagnose", false, "Check DNS, connectivity, proxy and config/cache paths and exit")
+	fs.BoolVar(&flags.Schema, "schema", false, "Print the JSON Schema of --format json output and exit")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s [list] [options]\n       %s config init|check|migrate [options]\n       %s cache list|clear [options]\n\n", os.Args[0], os.Args[0], os.Args[0])
 		fmt.Fprintln(out, "Options:")
-		fmt.Fprintln(out, "  -c, --city        City segment used in the kvartersmenyn URL (can be set in config)")
-		fmt.Fprintln(out, "  -a, --area        Area slug from kvartersmenyn, e.g. garda_161 (repeat or comma-separated)")
+		fmt.Fprintln(out, "  -c, --city        City segment(s) used in the kvartersmenyn URL, comma-separated (can be set in config)")
+		fmt.Fprintln(out, "  -a, --area        Area slug (garda_161) or city:area (repeat or comma-separated)")
+		fmt.Fprintln(out, "  --areas-match P   Only use areas whose city/area label matches a glob or substring")
+		fmt.Fprintln(out, "  --exclude-area A  Skip an area by slug or city/area label (can be repeated or comma-separated)")
+		fmt.Fprintln(out, "  --stdin           Read city/area targets or URLs from stdin, one per line")
+		fmt.Fprintln(out, "  --from-json FILE  Re-render a file written by --format json (filters, sorting and --format apply)")
+		fmt.Fprintln(out, "  --yes             Fetch more than max_areas areas (default 20) without asking")
+		fmt.Fprintln(out, "  --postcode CODE   Resolve a postcode to area slug(s) instead of --area")
 		fmt.Fprintln(out, "  -n, --name        Filter by restaurant name (fuzzy, case-insensitive)")
+		fmt.Fprintln(out, "  --name-exact      Match --name exactly (case-insensitive) instead of fuzzy")
+		fmt.Fprintln(out, "  --case-sensitive  Match --name, --menu and --search verbatim: case-sensitive substrings, no fuzzy matching")
 		fmt.Fprintln(out, "  -m, --menu        Filter by menu text (fuzzy, case-insensitive)")
-		fmt.Fprintln(out, "  -s, --search      Filter both name and menu (fuzzy, case-insensitive)")
-		fmt.Fprintln(out, "  -d, --day         Day of week to fetch (mon, tue, wed, thu, fri, sat, sun or 1-7)")
+		fmt.Fprintln(out, "  --category C      Only show restaurants of this cuisine or category, e.g. italian")
+		fmt.Fprintln(out, "  --infer-category  Guess each restaurant's category from its name and menu (implied by --category)")
+		fmt.Fprintln(out, "  --address         Filter by address, e.g. a street name (fuzzy, case-insensitive)")
+		fmt.Fprintln(out, "  -s, --search      Filter name, menu and address (fuzzy, case-insensitive)")
+		fmt.Fprintln(out, "  -d, --day         Day to fetch (mon-sun, mån-sön, 1-7, today, tomorrow or \"next monday\")")
 		fmt.Fprintln(out, "  -C, --cache-dir   Directory for cached HTML (empty to disable, can be set in config)")
-		fmt.Fprintln(out, "  -t, --cache-ttl   How long to reuse cached HTML (e.g. 6h, 2h)")
+		fmt.Fprintln(out, "  --cache-name-template T  Cache file name with {city}, {area} and {day}")
+		fmt.Fprintln(out, "  -t, --cache-ttl   How long to reuse cached HTML (e.g. 6h, 2h, auto or until:10:00)")
+		fmt.Fprintln(out, "  --respect-cache-control  Reuse a page as long as the site's Cache-Control or Expires allows instead")
+		fmt.Fprintln(out, "  --cache-history-list  List the kept cache snapshots (cache_history) for the areas and day, then exit")
+		fmt.Fprintln(out, "  --price-currency  Currency assumed for prices without a marker (SEK or EUR)")
+		fmt.Fprintln(out, "  --price-format F  How prices are shown: raw (as on the site), kr (129 kr) or symbol (129:-)")
+		fmt.Fprintln(out, "  --max-price       Only show restaurants priced at or below this amount in SEK")
+		fmt.Fprintln(out, "  --dish-price      Apply --max-price to dishes with their own price in the menu")
+		fmt.Fprintln(out, "  --has-link        Only show restaurants that link to their own page")
+		fmt.Fprintln(out, "  --missing FIELD   Only show restaurants missing name, price, address, phone, link or menu")
+		fmt.Fprintln(out, "  --sort ORDER      Order restaurants: site (default) or near-now")
+		fmt.Fprintln(out, "  --near-now        Order by how much of the lunch window is left (same as --sort near-now)")
+		fmt.Fprintln(out, "  --open-now        Hide restaurants whose listed lunch hours have ended")
+		fmt.Fprintln(out, "  --limit-per-city N  Show at most N restaurants per city across its areas (0 = all)")
+		fmt.Fprintln(out, "  --rotate          Pick one matching restaurant for the day, varying it from recent days")
+		fmt.Fprintln(out, "  --sort-areas O    Order areas: config (default) or count (most matches first)")
+		fmt.Fprintln(out, "  --highlight-updated  Mark restaurants whose menu changed since the previous fetch")
+		fmt.Fprintln(out, "  --changed-only    Only show restaurants whose menu changed since the previous snapshot (needs cache_history)")
+		fmt.Fprintln(out, "  --format FORMAT   Output format: "+strings.Join(outputFormats, ", ")+" (can be set in config)")
+		fmt.Fprintln(out, "  --output-dir DIR  Write each area and day to its own file in DIR")
+		fmt.Fprintln(out, "  --json-stream     Print one JSON object per area and day as it is done (NDJSON)")
+		fmt.Fprintln(out, "  --summary-json    Add run statistics (cache hits, prices, timings) to JSON or NDJSON output")
+		fmt.Fprintln(out, "  --menu-lines N    Show at most N menu lines per restaurant (0 shows all)")
+		fmt.Fprintln(out, "  --wrap MODE       Wrap long lines: word (default), off or char")
+		fmt.Fprintln(out, "  --top-words N     Print the N most frequent menu words (stop words removed) instead of the menus")
+		fmt.Fprintln(out, "  --separator S     Between areas in text output: rule (default), blank or none")
+		fmt.Fprintln(out, "  --show-url        Show the page URL each area was fetched from in the header")
+		fmt.Fprintln(out, "  --tight           Omit the blank line between restaurants")
+		fmt.Fprintln(out, "  --color WHEN      Color output: auto (default), always or never")
+		fmt.Fprintln(out, "  --post-process CMD  Pipe each area's restaurants as JSON through CMD before printing")
+		fmt.Fprintln(out, "  --translate       Show each menu line translated by translate_cmd from config")
+		fmt.Fprintln(out, "  --rate-limit R    Max live requests, e.g. 2/s or 30/m (cache hits are not limited)")
+		fmt.Fprintln(out, "  -v, --verbose     Log fetches, cache use and removed entries to stderr")
+		fmt.Fprintln(out, "  --log-format F    Operational logs on stderr: text (default) or json")
+		fmt.Fprintln(out, "  --log-file PATH   Append operational logs and errors to PATH instead of stderr (rotated at 10 MB)")
+		fmt.Fprintln(out, "  --strict-parse    Fail an area (exit 1) when a live page yields no restaurants or many incomplete ones")
+		fmt.Fprintln(out, "  --metrics-file P  Write Prometheus textfile metrics (durations, cache hits, results per area) to P")
+		fmt.Fprintln(out, "  --min-results N   Exit 1 when fewer than N restaurants matched across all areas")
+		fmt.Fprintln(out, "  --on-success CMD  Run shell command CMD after a run with matches (count in $KVARTERSMENYN_MATCHES)")
+		fmt.Fprintln(out, "  --on-empty CMD    Run shell command CMD after a run without matches")
+		fmt.Fprintln(out, "  --fail-fast       Stop at the first area that fails (exit 1; default when piped or redirected)")
+		fmt.Fprintln(out, "  --continue        Process all areas, report failures at the end (default in a terminal, exit 1 if any failed)")
+		fmt.Fprintln(out, "  --repeat          Fetch once, then prompt for filter queries until q")
+		fmt.Fprintln(out, "  --watch INTERVAL  Fetch again every interval (e.g. 5m) and print the results each time")
+		fmt.Fprintln(out, "  --watch-diff      With --watch, only print what changed since the previous cycle")
+		fmt.Fprintln(out, "  --exit-on-change  With --watch, exit 0 the first time the results change")
+		fmt.Fprintln(out, "  --explain         Show why each restaurant matched (substring, normalized, fuzzy)")
+		fmt.Fprintln(out, "  --expand-subareas  Fetch sub-areas when an umbrella area lists no restaurants")
+		fmt.Fprintln(out, "  --show-closed     Keep closed/placeholder entries (hidden by default)")
+		fmt.Fprintln(out, "  --week            Fetch every weekday (mon-fri) of the current week")
+		fmt.Fprintln(out, "  --compare-days    With --week and --name, show each matched restaurant's week as a table, a column per day")
+		fmt.Fprintln(out, "  --merge-days R    Merge a range of days (e.g. mon-fri) into one deduped list")
+		fmt.Fprintln(out, "  --compare         Show exactly two areas side by side")
+		fmt.Fprintln(out, "  --buckets         Group results into price ranges (under 100, 100-129, ...)")
+		fmt.Fprintln(out, "  --header K:V      Extra request header (repeatable, overrides config http_headers)")
+		fmt.Fprintln(out, "  --save-html DIR   Also write each area's HTML to DIR (for bug reports)")
 		fmt.Fprintf(out, "  -f, --config      Path to YAML config (default: %s)\n", defaultConfigPath())
 		fmt.Fprintln(out, "  -i, --init-config Run the interactive config setup and exit")
+		fmt.Fprintln(out, "  --edit-config     Open the config in $EDITOR (creating a template), then validate it")
+		fmt.Fprintln(out, "  --edit-areas      List the config's areas and remove, reorder or relabel them interactively")
+		fmt.Fprintln(out, "  --print-config    Print the merged flags, config and defaults as YAML and exit")
 		fmt.Fprintln(out, "  -h, --help        Show help and exit")
 		fmt.Fprintln(out, "  --version     Show version and exit")
+		fmt.Fprintln(out, "  --discover CITY   List the areas (name and slug) for a city and exit")
+		fmt.Fprintln(out, "  --self-test   Fetch a known area live and check that the scraper still works")
+		fmt.Fprintln(out, "  --diagnose    Check DNS, connectivity, proxy and config/cache paths and exit")
+		fmt.Fprintln(out, "  --schema          Print the JSON Schema of --format json output and exit")
+	}
+	fs.Parse(args)
+
+	if err := setupLogging(flags.LogFormat, flags.Verbose, flags.LogFile); err != nil {
+		log.Fatal(err)
 	}
-	flag.Parse()
 
 	if flags.Help {
-		flag.Usage()
+		fs.Usage()
 		return
 	}
 
@@ -129,10 +513,51 @@ func main() {
 		return
 	}
 
+	if flags.EditCfg {
+		os.Exit(runEditConfig(flags.Config))
+	}
+
+	if flags.EditAreas {
+		os.Exit(runEditAreas(flags.Config))
+	}
+
+	if flags.SelfTest {
+		os.Exit(runSelfTest())
+	}
+
+	if flags.Schema {
+		if err := writeSchema(os.Stdout); err != nil {
+			log.Fatalf("could not write schema: %v", err)
+		}
+		return
+	}
+
 	// Load config (if any). If missing and no --area, prompt the user once.
 	cfg, err := loadConfig(flags.Config)
+	if flags.Diagnose {
+		os.Exit(runDiagnose(flags, cfg, err))
+	}
+	if city := strings.TrimSpace(flags.Discover); city != "" {
+		if cfg == nil {
+			cfg = &Config{}
+		}
+		// Discovery needs the cache settings but no areas.
+		discoverFlags := flags
+		discoverFlags.City = city
+		discoverFlags.Areas = nil
+		discoverFlags.Stdin = false
+		opts, err := mergeOptions(cfg, discoverFlags)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(runDiscover(opts, city))
+	}
+	if err != nil && isConfigURL(flags.Config) {
+		// There is nothing to write a prompted config to.
+		log.Fatal(err)
+	}
 	if err != nil || cfg == nil || len(configAreas(cfg)) == 0 {
-		if len(flags.Areas) == 0 {
+		if len(flags.Areas) == 0 && flags.Postcode == "" && !flags.Stdin && flags.FromJSON == "" {
 			fmt.Println("No valid config found. We need at least one kvartersmenyn URL and (optional) cache TTL.")
 			promptAndSaveConfig(flags.Config)
 			return
@@ -146,80 +571,597 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if flags.Postcode != "" {
+		if opts.Areas, err = pickPostcodeAreas(flags.Postcode, opts.Areas); err != nil {
+			log.Fatal(err)
+		}
+	}
 	if day, ok := parseDayFlag(flags.Day); ok {
 		opts.Day = day
 	} else if flags.Day != "" {
-		log.Fatalf("invalid --day value: %q (use mon/tue/... or 1-7)", flags.Day)
+		log.Fatalf("invalid --day value: %q (use mon/tue/..., mån/tis/..., 1-7, today, tomorrow or \"next monday\")", flags.Day)
 	} else {
 		opts.Day = weekdayToDay(time.Now().Weekday())
 	}
 
+	if (opts.Sort == sortNearNow || opts.OpenNow) && (opts.Day != weekdayToDay(time.Now().Weekday()) || opts.Week || len(opts.MergeDays) > 0) {
+		log.Fatal("--near-now and --open-now only work for today's menu")
+	}
+
+	wrapMode = opts.Wrap
+	areaSeparator = opts.Separator
+	if colorEnabled, err = resolveColor(flags.Color); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(flags.Areas) > 0 {
+		if opts.Areas, err = resolveAreaSlugs(opts); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if flags.PrintCfg {
+		if err := printResolvedConfig(os.Stdout, opts, configSources(flags, cfg)); err != nil {
+			log.Fatalf("could not print config: %v", err)
+		}
+		return
+	}
+	if flags.CacheHistoryList {
+		os.Exit(runCacheHistoryList(opts))
+	}
+
+	if err := confirmAreaCount(opts); err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.Repeat {
+		os.Exit(runRepeat(opts))
+	}
+	if opts.Watch > 0 {
+		os.Exit(runWatch(opts))
+	}
+	if opts.Compare && len(opts.Areas) != 2 {
+		log.Fatalf("--compare needs exactly two areas, got %d", len(opts.Areas))
+	}
+
+	// Ctrl-C cancels in-flight fetches; results gathered so far still print.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// One timeout covers all requests in this run.
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(sigCtx, 15*time.Second)
 	defer cancel()
 
-	nameQuery := strings.TrimSpace(opts.Name)
-	menuQuery := strings.TrimSpace(opts.Menu)
-	combinedQuery := strings.TrimSpace(opts.Search)
-	combinedQueryRaw := combinedQuery
+	nameQuery, menuQuery := effectiveQueries(opts)
+	combinedQueryRaw := strings.TrimSpace(opts.Search)
+
+	// --week runs every weekday; otherwise just the requested day.
+	days := []int{opts.Day}
+	if opts.Week {
+		days = []int{1, 2, 3, 4, 5}
+	}
+
+	// Each area is fetched for every day; --from-json instead replays the
+	// areas and days in the file.
+	var targets []areaResult
+	if opts.FromJSON != "" {
+		targets = opts.Archive
+	} else {
+		for _, area := range opts.Areas {
+			for _, day := range days {
+				targets = append(targets, areaResult{Area: area, Day: day, Days: opts.MergeDays})
+			}
+		}
+	}
+
+	var failed []string
+	var results []areaResult
+	completed := 0
+	streamed := 0
+	matched := 0
+	var written []string
+	var stats runStats
+	runStarted := time.Now()
+	for _, target := range targets {
+		area, day := target.Area, target.Day
+		if sigCtx.Err() != nil {
+			break
+		}
+		dayOpts := opts
+		dayOpts.Day = day
+		started := time.Now()
 
-	for _, area := range opts.Areas {
 		// Fetch HTML (cache-first), parse it, then filter and print.
-		reader, sourceInfo, err := loadAreaReader(ctx, opts.CacheDir, area, opts.Day, opts.CacheTTL)
+		var restaurants []Restaurant
+		var sourceInfo SourceInfo
+		var err error
+		if opts.FromJSON != "" {
+			restaurants, sourceInfo = target.Restaurants, target.Info
+			restaurants = applyFilters(restaurants, dayOpts, nameQuery, menuQuery)
+		} else if len(opts.MergeDays) > 0 {
+			restaurants, sourceInfo, err = loadMergedDays(ctx, dayOpts, area, nameQuery, menuQuery)
+		} else {
+			restaurants, sourceInfo, err = loadRestaurants(ctx, dayOpts, area)
+			restaurants = applyFilters(restaurants, dayOpts, nameQuery, menuQuery)
+		}
 		if err != nil {
-			log.Fatalf("could not fetch data for %s: %v", areaLabelWithDay(area, opts.Day), err)
+			if sigCtx.Err() != nil {
+				break
+			}
+			failed = append(failed, areaLabelWithDay(area, day))
+			stats.addArea(areaResult{Area: area, Day: day, Days: target.Days, Info: SourceInfo{Label: areaLabel(area)}}, time.Since(started), true)
+			if opts.FailFast {
+				writeRunMetrics(opts, stats, time.Since(runStarted), len(failed))
+				log.Fatal(err)
+			}
+			slog.Error(err.Error(), "area", areaLabel(area))
+			continue
 		}
 
-		restaurants, err := parseRestaurants(reader)
-		reader.Close()
-		if err != nil {
-			log.Fatalf("could not parse page for %s: %v", areaLabel(area), err)
+		completed++
+		result := areaResult{Area: area, Day: day, Days: target.Days, Info: sourceInfo, Restaurants: restaurants}
+		if opts.PostProcess != "" {
+			result.Restaurants = postProcess(ctx, opts.PostProcess, result)
+		}
+		stats.addArea(result, time.Since(started), false)
+		matched += len(result.Restaurants)
+		if opts.OutputDir != "" {
+			path, err := writeAreaFile(opts.OutputDir, result, opts, nameQuery, menuQuery, combinedQueryRaw, time.Now())
+			if err != nil {
+				log.Fatal(err)
+			}
+			written = append(written, path)
+			continue
+		}
+		// Text is printed as soon as each area is done unless the
+		// areas are reordered, capped per city or picked from afterwards.
+		buffered := opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || opts.Rotate || opts.TopWords > 0 || opts.CompareDays
+		if opts.Format == formatText && !opts.Compare && !buffered {
+			printAreaText(result, opts, nameQuery, menuQuery, combinedQueryRaw)
+			continue
+		}
+		if opts.Format == formatDebug {
+			printAreaDebug(result)
+			continue
+		}
+		if opts.JSONStream {
+			if err := writeJSONLine(os.Stdout, toJSONArea(result)); err != nil {
+				log.Fatalf("could not write JSON: %v", err)
+			}
+			streamed += len(result.Restaurants)
+			stats.addPrices(result.Restaurants)
+			continue
 		}
+		results = append(results, result)
+	}
+
+	if opts.OutputDir != "" {
+		fmt.Printf("Wrote %d file(s) to %s:\n", len(written), opts.OutputDir)
+		for _, path := range written {
+			fmt.Println(path)
+		}
+	}
 
-		if combinedQuery != "" {
-			if nameQuery == "" {
-				nameQuery = combinedQuery
+	if opts.Rotate {
+		results = rotatePick(results, opts, time.Now())
+		if len(results) == 0 && opts.Format == formatText {
+			fmt.Fprintln(output, "No matching restaurants to pick from.")
+		}
+	}
+	if opts.SortAreas == sortAreasCount {
+		sort.SliceStable(results, func(i, j int) bool {
+			return len(results[i].Restaurants) > len(results[j].Restaurants)
+		})
+	}
+	var hiddenPerCity map[string]int
+	if opts.LimitPerCity > 0 {
+		hiddenPerCity = limitPerCity(results, opts.LimitPerCity)
+	}
+	if (opts.SortAreas == sortAreasCount || opts.LimitPerCity > 0 || opts.Rotate) && opts.Format == formatText && !opts.Compare {
+		for i, result := range results {
+			printAreaText(result, opts, nameQuery, menuQuery, combinedQueryRaw)
+			if hidden := hiddenPerCity[result.Area.City]; hidden > 0 && lastOfCity(results, i) {
+				printLine(fmt.Sprintf("(+%d more in %s)", hidden, result.Area.City))
+				fmt.Fprintln(output)
 			}
-			if menuQuery == "" {
-				menuQuery = combinedQuery
+		}
+	}
+
+	if opts.Compare && opts.Format == formatText {
+		switch len(results) {
+		case 2:
+			printCompare(results[0], results[1], opts, nameQuery, menuQuery, combinedQueryRaw)
+		default:
+			for _, result := range results {
+				printAreaText(result, opts, nameQuery, menuQuery, combinedQueryRaw)
 			}
-			restaurants = filterCombined(restaurants, nameQuery, menuQuery)
-		} else {
-			if nameQuery != "" {
-				restaurants = filterRestaurants(restaurants, nameQuery)
+		}
+	}
+
+	summary := jsonStreamSummary{Results: completed, Restaurants: streamed, Failed: failed, Interrupted: sigCtx.Err() != nil}
+	if summary.Failed == nil {
+		summary.Failed = []string{}
+	}
+	if opts.SummaryJSON {
+		if !opts.JSONStream {
+			summary.Results, summary.Restaurants = len(results), 0
+			for _, result := range results {
+				summary.Restaurants += len(result.Restaurants)
+				stats.addPrices(result.Restaurants)
 			}
-			if menuQuery != "" {
-				restaurants = filterByMenu(restaurants, menuQuery)
+		}
+		stats.DurationMS = time.Since(runStarted).Milliseconds()
+		summary.runStats = &stats
+	}
+	if opts.JSONStream {
+		if err := writeJSONLine(os.Stdout, map[string]jsonStreamSummary{"summary": summary}); err != nil {
+			log.Fatalf("could not write JSON: %v", err)
+		}
+	}
+
+	switch {
+	case opts.JSONStream || opts.OutputDir != "":
+		// Already written per area.
+	case opts.TopWords > 0 && opts.Format == formatJSON:
+		if err := writeTopWordsJSON(os.Stdout, topMenuWords(results, opts.StopWords, opts.TopWords)); err != nil {
+			log.Fatalf("could not write JSON: %v", err)
+		}
+	case opts.TopWords > 0:
+		printTopWords(topMenuWords(results, opts.StopWords, opts.TopWords), results)
+	case opts.CompareDays:
+		printCompareDays(results, opts, nameQuery, menuQuery, combinedQueryRaw)
+	case opts.Format == formatJSON && opts.SummaryJSON:
+		if err := writeJSONWithSummary(os.Stdout, results, summary); err != nil {
+			log.Fatalf("could not write JSON: %v", err)
+		}
+	case opts.Format == formatJSON:
+		if err := writeJSON(os.Stdout, results); err != nil {
+			log.Fatalf("could not write JSON: %v", err)
+		}
+	case opts.Format == formatICal:
+		if err := writeICal(os.Stdout, results, time.Now()); err != nil {
+			log.Fatalf("could not write iCalendar: %v", err)
+		}
+	case opts.Format == formatVCard:
+		if err := writeVCard(os.Stdout, results); err != nil {
+			log.Fatalf("could not write vCard: %v", err)
+		}
+	}
+
+	writeRunMetrics(opts, stats, time.Since(runStarted), len(failed))
+
+	if sigCtx.Err() != nil {
+		log.Printf("interrupted after %d of %d area(s)", completed, len(targets))
+		os.Exit(130)
+	}
+
+	switch {
+	case matched > 0 && opts.OnSuccess != "":
+		runHook("on-success", opts.OnSuccess, matched, len(targets), len(failed))
+	case matched == 0 && opts.OnEmpty != "":
+		runHook("on-empty", opts.OnEmpty, matched, len(targets), len(failed))
+	}
+
+	tooFew := opts.MinResults > 0 && matched < opts.MinResults
+	if tooFew {
+		log.Printf("only %d restaurant(s) matched across %d area(s), expected at least %d (--min-results)", matched, len(targets), opts.MinResults)
+	}
+	if len(failed) > 0 {
+		log.Printf("%d of %d area(s) failed: %s", len(failed), len(targets), strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+	if tooFew {
+		os.Exit(1)
+	}
+}
+
+// effectiveQueries expands --search into the name and menu queries unless
+// --name or --menu were given explicitly.
+func effectiveQueries(opts Options) (string, string) {
+	nameQuery := strings.TrimSpace(opts.Name)
+	menuQuery := strings.TrimSpace(opts.Menu)
+	if combined := strings.TrimSpace(opts.Search); combined != "" {
+		if nameQuery == "" {
+			nameQuery = combined
+		}
+		if menuQuery == "" {
+			menuQuery = combined
+		}
+	}
+	return nameQuery, menuQuery
+}
+
+// applyFilters runs the name, menu, price, missing-field, has-link and
+// open-now filters, then applies --sort. A --search query has
+// already been expanded into nameQuery and menuQuery.
+func applyFilters(restaurants []Restaurant, opts Options, nameQuery, menuQuery string) []Restaurant {
+	if opts.NameExact {
+		// Exact names bypass fuzzy name matching; menu filters still apply.
+		restaurants = filterExactName(restaurants, opts.Name, opts.CaseSensitive)
+		if menuQuery != "" && opts.CaseSensitive {
+			restaurants = filterVerbatim(restaurants, "", menuQuery, "", false)
+		} else if menuQuery != "" {
+			restaurants = filterByMenu(restaurants, menuQuery)
+		}
+	} else if opts.CaseSensitive {
+		search := strings.TrimSpace(opts.Search)
+		restaurants = filterVerbatim(restaurants, nameQuery, menuQuery, search, search != "")
+	} else if strings.TrimSpace(opts.Search) != "" {
+		restaurants = filterCombined(restaurants, nameQuery, menuQuery, strings.TrimSpace(opts.Search))
+	} else {
+		if nameQuery != "" {
+			restaurants = filterRestaurants(restaurants, nameQuery)
+		}
+		if menuQuery != "" {
+			restaurants = filterByMenu(restaurants, menuQuery)
+		}
+	}
+	if category := strings.TrimSpace(opts.Category); category != "" {
+		restaurants = filterCategory(restaurants, category)
+	}
+	if address := strings.TrimSpace(opts.Address); address != "" {
+		restaurants = filterByAddress(restaurants, address)
+	}
+	if opts.MaxPrice > 0 {
+		restaurants = filterByMaxPrice(restaurants, opts.MaxPrice, opts.DishPrice)
+	}
+	if len(opts.Missing) > 0 {
+		restaurants = filterMissing(restaurants, opts.Missing)
+	}
+	if opts.HasLink {
+		restaurants = filterHasLink(restaurants)
+	}
+	if opts.OpenNow {
+		restaurants = filterOpenNow(restaurants, time.Now())
+	}
+	switch opts.Sort {
+	case sortNearNow:
+		sortByNearNow(restaurants, time.Now())
+	}
+	return restaurants
+}
+
+// restaurantFields are the fields --missing can check.
+var restaurantFields = []string{"name", "price", "address", "phone", "link", "menu"}
+
+// parseMissing accepts one field or a comma-separated list.
+func parseMissing(input string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(input, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(restaurantFields, field) {
+			return nil, fmt.Errorf("invalid --missing field %q (use %s)", field, strings.Join(restaurantFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// filterMissing keeps restaurants where every given field is empty.
+func filterMissing(restaurants []Restaurant, fields []string) []Restaurant {
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		missing := true
+		for _, field := range fields {
+			if !fieldEmpty(r, field) {
+				missing = false
+				break
 			}
 		}
+		if missing {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
 
-		if len(restaurants) == 0 {
-			printHeader(sourceInfo, nameQuery, menuQuery, combinedQueryRaw)
-			noHitMsg(nameQuery, menuQuery, combinedQueryRaw)
+// filterHasLink keeps restaurants whose link resolves to a page on the web,
+// so listing-only entries and "#" or javascript: links are dropped.
+func filterHasLink(restaurants []Restaurant) []Restaurant {
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		if strings.TrimSpace(r.Link) == "" || strings.HasPrefix(strings.TrimSpace(r.Link), "#") {
 			continue
 		}
+		link, err := url.Parse(absoluteLink(r.Link))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+func fieldEmpty(r Restaurant, field string) bool {
+	switch field {
+	case "name":
+		return strings.TrimSpace(r.Name) == ""
+	case "price":
+		return strings.TrimSpace(r.Price) == ""
+	case "address":
+		return strings.TrimSpace(r.Address) == ""
+	case "phone":
+		return strings.TrimSpace(r.Phone) == ""
+	case "link":
+		return strings.TrimSpace(r.Link) == ""
+	case "menu":
+		return len(r.Menu) == 0
+	default:
+		return false
+	}
+}
+
+const (
+	sortSite    = "site"
+	sortNearNow = "near-now"
+)
+
+const (
+	sortAreasConfig = "config"
+	sortAreasCount  = "count"
+)
+
+// loadRestaurants fetches (cache-first) and parses one area.
+func loadRestaurants(ctx context.Context, opts Options, area AreaConfig) ([]Restaurant, SourceInfo, error) {
+	if at, ok := knownEmpty(opts, area, opts.Day, time.Now()); ok {
+		slog.Debug("no lunches on last fetch, skipping", "area", areaLabel(area), "day", dayLabel(opts.Day), "checked", at)
+		return nil, SourceInfo{Label: areaLabel(area), Source: "cache", CacheUpdated: at, URL: areaURL(area, opts.Day)}, nil
+	}
+	reader, sourceInfo, err := loadAreaReader(ctx, opts, area, opts.Day)
+	if err != nil {
+		return nil, SourceInfo{}, fmt.Errorf("could not fetch data for %s: %w", areaLabelWithDay(area, opts.Day), err)
+	}
+	if opts.SaveHTML != "" {
+		reader, err = saveHTMLCopy(reader, opts.SaveHTML, area, opts.Day)
+		if err != nil {
+			return nil, SourceInfo{}, fmt.Errorf("could not read page for %s: %w", areaLabelWithDay(area, opts.Day), err)
+		}
+	}
 
-		printHeader(sourceInfo, nameQuery, menuQuery, combinedQueryRaw)
-		for _, r := range restaurants {
-			printLine(fmt.Sprintf("%s — %s", r.Name, r.Price))
-			if r.Address != "" {
-				printLine(fmt.Sprintf("  %s", r.Address))
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, SourceInfo{}, fmt.Errorf("could not read page for %s: %w", areaLabelWithDay(area, opts.Day), err)
+	}
+	restaurants, err := parseRestaurants(bytes.NewReader(data))
+	if err != nil {
+		return nil, SourceInfo{}, fmt.Errorf("could not parse page for %s: %w", areaLabel(area), err)
+	}
+	var children []AreaConfig
+	if len(restaurants) == 0 && area.Area != "" {
+		children = subareaLinks(data, area)
+	}
+	if opts.StrictParse && sourceInfo.Source == "live" {
+		if err := checkParse(data, restaurants, children, opts.Day); err != nil {
+			return nil, SourceInfo{}, fmt.Errorf("suspicious parse of %s (--strict-parse): %w", areaLabelWithDay(area, opts.Day), err)
+		}
+	}
+	if sourceInfo.Source == "live" {
+		recordEmpty(opts, area, opts.Day, len(restaurants) == 0 && len(children) == 0)
+	}
+	if len(restaurants) == 0 && area.Area != "" {
+		if len(children) > 0 {
+			if opts.ExpandSubareas {
+				return loadSubareas(ctx, opts, area, children)
 			}
-			if r.Phone != "" {
-				printLine(fmt.Sprintf("  Tel: %s", r.Phone))
+		} else if sourceInfo.Source == "cache" {
+			// An empty cached page may be an interstitial or a truncated
+			// write; fetch live once. A zero TTL skips the cache, so this
+			// cannot loop. If the fetch fails the empty result stands.
+			slog.Debug("cached page has no restaurants, fetching live", "area", areaLabel(area), "day", dayLabel(opts.Day))
+			liveOpts := opts
+			liveOpts.CacheTTL = 0
+			live, liveInfo, err := loadRestaurants(ctx, liveOpts, area)
+			if err == nil {
+				return live, liveInfo, nil
 			}
-			if r.Link != "" {
-				printLine(fmt.Sprintf("  Link: %s", r.Link))
+			slog.Debug("live refetch failed, using cached page", "area", areaLabel(area), "error", err)
+		}
+	}
+	for i := range restaurants {
+		restaurants[i].Menu = cleanMenuLines(restaurants[i].Menu, opts.MenuMinLine)
+	}
+	// Compare the whole page with yesterday's, before closed and
+	// blocklisted restaurants are dropped from it.
+	if warning := staleMenuWarning(opts, area, restaurants, time.Now()); warning != "" {
+		sourceInfo.Warning = warning
+	}
+	if !opts.ShowClosed {
+		restaurants = filterClosed(restaurants)
+	}
+	if len(opts.Blocklist) > 0 {
+		var removed int
+		restaurants, removed = filterBlocklist(restaurants, opts.Blocklist)
+		if removed > 0 {
+			slog.Debug("removed blocklisted restaurants", "area", areaLabel(area), "count", removed)
+		}
+	}
+	applyPriceCurrency(restaurants, opts.Day, opts.PriceCurrency, opts.EURRate)
+	if opts.InferCategory {
+		for i := range restaurants {
+			if restaurants[i].Category == "" {
+				restaurants[i].Category = inferCategory(restaurants[i])
+				restaurants[i].CategoryInferred = restaurants[i].Category != ""
 			}
-			if len(r.Menu) > 0 {
-				printLine("  Menu:")
-				for _, line := range r.Menu {
-					printLine(fmt.Sprintf("    - %s", line))
-				}
+		}
+	}
+	if opts.HighlightUpdated {
+		markUpdated(opts.CacheDir, opts.CacheNameTemplate, area, opts.Day, sourceInfo.Source == "live", restaurants)
+	}
+	if opts.ChangedOnly {
+		restaurants = filterChanged(opts, area, sourceInfo.CacheUpdated, restaurants)
+	}
+	return restaurants, sourceInfo, nil
+}
+
+func printRestaurant(r Restaurant, opts Options, menuQuery string) {
+	title := fmt.Sprintf("%s — %s", r.Name, formatPrice(r, opts.PriceFormat))
+	if menuQuery != "" {
+		title += formatHits(countMenuHits(r.Menu, menuQuery, opts.CaseSensitive))
+	}
+	if r.Updated {
+		title += " ★ updated"
+	}
+	printStyledLine(title, styleBold)
+	if opts.Explain && len(r.Matches) > 0 {
+		printLine(fmt.Sprintf("  Match: %s", explainMatch(r)))
+	}
+	if len(r.Days) > 0 {
+		printLine(fmt.Sprintf("  Days: %s", strings.Join(r.Days, ", ")))
+	}
+	if r.Address != "" {
+		printLine(fmt.Sprintf("  %s", r.Address))
+	}
+	if r.Phone != "" {
+		printLine(fmt.Sprintf("  Tel: %s", r.Phone))
+	}
+	if r.Category != "" {
+		if r.CategoryInferred {
+			printLine(fmt.Sprintf("  Category: %s (guessed)", r.Category))
+		} else {
+			printLine(fmt.Sprintf("  Category: %s", r.Category))
+		}
+	}
+	if r.Hours != "" {
+		printLine(fmt.Sprintf("  Lunch: %s", r.Hours))
+	}
+	if r.Link != "" {
+		printLine(fmt.Sprintf("  Link: %s", r.Link))
+	}
+	extraKeys := make([]string, 0, len(r.Extra))
+	for key := range r.Extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		printLine(fmt.Sprintf("  %s: %s", key, formatExtra(r.Extra[key])))
+	}
+	if len(r.Menu) > 0 {
+		if r.UnstructuredMenu {
+			printLine("  Menu (unstructured):")
+		} else {
+			printLine("  Menu:")
+		}
+		menu := r.Menu
+		if opts.MenuLines > 0 && len(menu) > opts.MenuLines {
+			menu = menu[:opts.MenuLines]
+		}
+		for _, line := range menu {
+			printLine(fmt.Sprintf("    - %s", line))
+			if translated := opts.Translator.translate(line); translated != "" {
+				printLine(fmt.Sprintf("      (%s)", translated))
 			}
-			fmt.Println()
+		}
+		if hidden := len(r.Menu) - len(menu); hidden > 0 {
+			printLine(fmt.Sprintf("    (+%d more)", hidden))
 		}
 	}
+	if !opts.Tight {
+		fmt.Fprintln(output)
+	}
 }
 
 func buildAreaURL(city, area string, day int) string {
@@ -229,6 +1171,15 @@ func buildAreaURL(city, area string, day int) string {
 	return fmt.Sprintf("https://www.kvartersmenyn.se/index.php/%s/area/%s/day/%d", city, area, day)
 }
 
+// areaURL is the page fetched for an area, or for the whole city when no
+// area is set.
+func areaURL(area AreaConfig, day int) string {
+	if area.Area == "" {
+		return buildCityURL(area.City, day)
+	}
+	return buildAreaURL(area.City, area.Area, day)
+}
+
 func buildCityURL(city string, day int) string {
 	if isNumericCity(city) {
 		return fmt.Sprintf("https://www.kvartersmenyn.se/index.php/find/_/city/%s/day/%d", city, day)
@@ -251,33 +1202,144 @@ func areaLabelWithDay(area AreaConfig, day int) string {
 	return label
 }
 
-func loadAreaReader(ctx context.Context, cacheDir string, area AreaConfig, day int, ttl time.Duration) (io.ReadCloser, SourceInfo, error) {
-	label := areaLabelWithDay(area, day)
-	cacheKey := area.Area
-	if cacheKey == "" {
-		cacheKey = "all"
+// defaultCacheNameTemplate names cached area pages, e.g.
+// goteborg_garda_161_day3.html. {area} is "all" for whole-city targets and
+// {day} is 1 (Monday) to 7 (Sunday).
+const defaultCacheNameTemplate = "{city}_{area}_day{day}.html"
+
+// parseCacheNameTemplate checks that a --cache-name-template keeps area
+// pages apart and stays visible to `cache list|clear`.
+func parseCacheNameTemplate(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultCacheNameTemplate, nil
+	}
+	for _, placeholder := range []string{"{city}", "{area}", "{day}"} {
+		if !strings.Contains(input, placeholder) {
+			return "", fmt.Errorf("invalid --cache-name-template %q (must contain %s)", input, placeholder)
+		}
 	}
-	cacheKey = fmt.Sprintf("%s_day%d", cacheKey, day)
-	if cache, modTime, ok := tryCache(cacheDir, area.City, cacheKey, ttl); ok {
-		return cache, SourceInfo{Label: label, Source: "cache", CacheUpdated: modTime}, nil
+	if strings.ContainsAny(input, `/\`) {
+		return "", fmt.Errorf("invalid --cache-name-template %q (must be a file name, not a path)", input)
 	}
+	if !strings.HasSuffix(input, ".html") {
+		return "", fmt.Errorf("invalid --cache-name-template %q (must end in .html)", input)
+	}
+	return input, nil
+}
 
-	// No cache hit; build URL and fetch live.
-	var url string
-	if area.Area == "" {
-		url = buildCityURL(area.City, day)
-	} else {
-		url = buildAreaURL(area.City, area.Area, day)
+// areaCacheName is the cache file name for one area and day.
+func areaCacheName(template string, area AreaConfig, day int) string {
+	slug := area.Area
+	if slug == "" {
+		slug = "all"
+	}
+	return strings.NewReplacer(
+		"{city}", area.City,
+		"{area}", slug,
+		"{day}", strconv.Itoa(day),
+	).Replace(template)
+}
+
+func loadAreaReader(ctx context.Context, opts Options, area AreaConfig, day int) (io.ReadCloser, SourceInfo, error) {
+	label := areaLabel(area)
+	url := areaURL(area, day)
+	cacheName := areaCacheName(opts.CacheNameTemplate, area, day)
+	ttl := effectiveCacheTTL(opts, day, time.Now())
+	// A zero TTL forces a live fetch (watch cycles, the empty-page
+	// refetch), so the server's hint must not turn the cache back on.
+	if opts.RespectCacheControl && ttl > 0 {
+		if serverTTL, ok := serverCacheTTL(opts.CacheDir, cacheName); ok {
+			slog.Debug("using the server's cache hint", "area", areaLabel(area), "day", dayLabel(day), "ttl", serverTTL.Round(time.Second), "configured", ttl)
+			ttl = serverTTL
+		}
+	}
+	if cache, modTime, ok := tryCache(opts.CacheDir, cacheName, ttl); ok {
+		slog.Debug("cache hit", "area", areaLabel(area), "day", dayLabel(day), "updated", modTime)
+		return cache, SourceInfo{Label: label, Source: "cache", CacheUpdated: modTime, URL: url}, nil
+	}
+
+	// No cache hit; fetch live.
+	slog.Debug("cache miss", "area", areaLabel(area), "day", dayLabel(day))
+	resp, err := fetchHTML(ctx, url, withAreaHeaders(opts, area))
+	if err != nil {
+		return nil, SourceInfo{}, err
 	}
-	resp, err := fetchHTML(ctx, url)
+	reader, cacheUpdated, err := cacheAndWrap(resp.Body, opts.CacheDir, cacheName)
 	if err != nil {
 		return nil, SourceInfo{}, err
 	}
-	reader, cacheUpdated := cacheAndWrap(resp.Body, cacheDir, area.City, cacheKey)
-	return reader, SourceInfo{Label: label, Source: "live", CacheUpdated: cacheUpdated}, nil
+	if opts.CacheHistory > 0 && !cacheUpdated.IsZero() {
+		saveSnapshot(opts.CacheDir, cacheName, opts.CacheHistory, cacheUpdated)
+	}
+	if opts.RespectCacheControl && !cacheUpdated.IsZero() {
+		saveFreshness(opts.CacheDir, cacheName, resp.Header, cacheUpdated)
+	}
+	info := SourceInfo{Label: label, Source: "live", CacheUpdated: cacheUpdated, URL: url}
+	if final := resp.Request.URL.String(); final != url {
+		info.FinalURL = final
+	}
+	return reader, info, nil
+}
+
+// withAreaHeaders returns opts with the area's own headers merged over the
+// global ones.
+func withAreaHeaders(opts Options, area AreaConfig) Options {
+	if len(area.Headers) == 0 {
+		return opts
+	}
+	headers := make(map[string]string, len(opts.Headers)+len(area.Headers))
+	for key, value := range opts.Headers {
+		headers[key] = value
+	}
+	for key, value := range area.Headers {
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(key))] = value
+	}
+	opts.Headers = headers
+	return opts
+}
+
+// httpClient is shared by all fetches so multi-area runs reuse connections
+// instead of dialing kvartersmenyn.se once per area.
+var httpClient = &http.Client{
+	Timeout: 12 * time.Second,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        16,
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: checkRedirect,
+}
+
+// maxRedirects caps how many hops a fetch follows.
+const maxRedirects = 5
+
+// checkRedirect logs each hop so URL scheme changes on the site can be
+// spotted, and gives up after maxRedirects.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > maxRedirects {
+		return fmt.Errorf("stopped after %d redirects from %s (last %s); the site may have moved its pages", maxRedirects, via[0].URL, req.URL)
+	}
+	slog.Debug("redirect", "from", via[len(via)-1].URL.String(), "to", req.URL.String())
+	return nil
 }
 
-func fetchHTML(ctx context.Context, url string) (*http.Response, error) {
+// fetchHTML does a live GET with the configured headers, waiting for the
+// rate limiter (if any) first.
+func fetchHTML(ctx context.Context, url string, opts Options) (*http.Response, error) {
+	if opts.Limiter != nil {
+		if err := opts.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -286,15 +1348,17 @@ func fetchHTML(ctx context.Context, url string) (*http.Response, error) {
 	// Use a normal browser UA to avoid trivial bot blocking.
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 	req.Header.Set("Accept-Language", "sv-SE,sv;q=0.9,en;q=0.8")
-
-	client := http.Client{
-		Timeout: 12 * time.Second,
+	for key, value := range opts.Headers {
+		req.Header.Set(key, value)
 	}
 
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := httpClient.Do(req)
 	if err != nil {
+		slog.Debug("fetch failed", "url", url, "duration_ms", time.Since(start).Milliseconds(), "error", err)
 		return nil, err
 	}
+	slog.Debug("fetch", "url", url, "status", resp.StatusCode, "duration_ms", time.Since(start).Milliseconds())
 
 	if resp.StatusCode >= 400 {
 		defer resp.Body.Close()
@@ -302,14 +1366,26 @@ func fetchHTML(ctx context.Context, url string) (*http.Response, error) {
 		return nil, fmt.Errorf("oväntad statuskod %d: %s", resp.StatusCode, string(body))
 	}
 
+	// Transcode to UTF-8 using the Content-Type header or <meta charset>, so
+	// the parser and the cache only ever see UTF-8.
+	body, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not detect page encoding: %w", err)
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}
+
 	return resp, nil
 }
 
-func tryCache(dir, city, area string, ttl time.Duration) (io.ReadCloser, time.Time, bool) {
+func tryCache(dir, name string, ttl time.Duration) (io.ReadCloser, time.Time, bool) {
 	if dir == "" || ttl <= 0 {
 		return nil, time.Time{}, false
 	}
-	cachePath := filepath.Join(dir, fmt.Sprintf("%s_%s.html", city, area))
+	cachePath := filepath.Join(dir, name)
 	info, err := os.Stat(cachePath)
 	if err != nil {
 		return nil, time.Time{}, false
@@ -324,20 +1400,20 @@ func tryCache(dir, city, area string, ttl time.Duration) (io.ReadCloser, time.Ti
 	return file, info.ModTime(), true
 }
 
-func cacheAndWrap(body io.ReadCloser, dir, city, area string) (io.ReadCloser, time.Time) {
+func cacheAndWrap(body io.ReadCloser, dir, name string) (io.ReadCloser, time.Time, error) {
 	defer body.Close()
 
 	// Read once, optionally write cache, then return a fresh reader.
 	data, err := io.ReadAll(body)
 	if err != nil {
-		log.Fatalf("could not read response body: %v", err)
+		return nil, time.Time{}, fmt.Errorf("could not read response body: %w", err)
 	}
 
 	var cacheUpdated time.Time
 	if dir != "" {
 		if err := os.MkdirAll(dir, 0o755); err == nil {
-			cachePath := filepath.Join(dir, fmt.Sprintf("%s_%s.html", city, area))
-			if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+			cachePath := filepath.Join(dir, name)
+			if err := writeFileAtomic(cachePath, data, 0o644); err != nil {
 				log.Printf("could not write cache (%s): %v", cachePath, err)
 			} else {
 				cacheUpdated = time.Now()
@@ -347,10 +1423,81 @@ func cacheAndWrap(body io.ReadCloser, dir, city, area string) (io.ReadCloser, ti
 		}
 	}
 
-	return io.NopCloser(bytes.NewReader(data)), cacheUpdated
+	return io.NopCloser(bytes.NewReader(data)), cacheUpdated, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it into place, so concurrent runs sharing a cache directory never
+// read a partially written page; the last writer wins.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// saveHTMLCopy writes the page to dir regardless of the cache settings and
+// returns a fresh reader over the same bytes.
+func saveHTMLCopy(body io.ReadCloser, dir string, area AreaConfig, day int) (io.ReadCloser, error) {
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	areaSlug := area.Area
+	if areaSlug == "" {
+		areaSlug = "all"
+	}
+	path := filepath.Join(expandHome(dir), fmt.Sprintf("kvartersmenyn_%s_%s_%s.html", area.City, areaSlug, dayLabel(day)))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("could not create directory for saved HTML (%s): %v", dir, err)
+	} else if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Printf("could not save HTML (%s): %v", path, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Saved HTML to %s\n", path)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// confirmAreaCount asks before a run fetches more than max_areas areas, so
+// a broad config does not fire dozens of live requests by accident. Without
+// a terminal it requires --yes instead.
+func confirmAreaCount(opts Options) error {
+	if len(opts.Areas) <= opts.MaxAreas || opts.Yes || opts.FromJSON != "" {
+		return nil
+	}
+	if !isInteractive() {
+		return fmt.Errorf("%d areas exceed max_areas (%d); pass --yes to fetch them all", len(opts.Areas), opts.MaxAreas)
+	}
+	fmt.Printf("This run fetches %d areas (max_areas is %d). Continue? (y/N): ", len(opts.Areas), opts.MaxAreas)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer != "y" && answer != "yes" && answer != "j" && answer != "ja" {
+		return errors.New("aborted")
+	}
+	return nil
 }
 
 func promptAndSaveConfig(path string) *Config {
+	if isConfigURL(path) {
+		log.Fatalf("config %s is a URL and cannot be written; edit the shared file instead", path)
+	}
 	reader := bufio.NewReader(os.Stdin)
 
 	var areas []AreaConfig
@@ -428,7 +1575,7 @@ func promptAndSaveConfig(path string) *Config {
 		}
 	}
 
-	fmt.Print("Cache TTL in Go duration format (default 6h): ")
+	fmt.Print("Cache TTL in Go duration format or auto (default 6h): ")
 	ttlInput, _ := reader.ReadString('\n')
 	ttlInput = strings.TrimSpace(ttlInput)
 	if ttlInput == "" {
@@ -529,30 +1676,126 @@ func filterRestaurants(restaurants []Restaurant, query string) []Restaurant {
 
 	var filtered []Restaurant
 	for _, r := range restaurants {
-		if matchesName(r.Name, queryLower, maxDistance) {
-			filtered = append(filtered, r)
+		if result := matchesName(r.Name, queryLower, maxDistance); result.Matched {
+			filtered = append(filtered, withMatches(r, fieldMatch{"name", result}))
 		}
 	}
 	return filtered
 }
 
-func matchesName(name, queryLower string, maxDistance int) bool {
+// filterBlocklist drops restaurants whose name fuzzy-matches any blocklist
+// entry and reports how many were removed.
+func filterBlocklist(restaurants []Restaurant, blocklist []string) ([]Restaurant, int) {
+	var kept []Restaurant
+	for _, r := range restaurants {
+		blocked := false
+		for _, entry := range blocklist {
+			if matchesName(r.Name, strings.ToLower(entry), fuzzThreshold(len(entry))).Matched {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			kept = append(kept, r)
+		}
+	}
+	return kept, len(restaurants) - len(kept)
+}
+
+// matchResult says whether and how a query matched.
+type matchResult struct {
+	Matched  bool
+	Reason   string
+	Distance int
+}
+
+// fieldMatch is a filter's result for one field, shown by --explain.
+type fieldMatch struct {
+	Field  string
+	Result matchResult
+}
+
+// withMatches returns r with matches appended to its Matches, without
+// sharing the slice with other copies of r.
+func withMatches(r Restaurant, matches ...fieldMatch) Restaurant {
+	r.Matches = append(slices.Clip(r.Matches), matches...)
+	return r
+}
+
+const (
+	matchSubstring  = "substring"
+	matchNormalized = "normalized"
+	matchFuzzy      = "fuzzy"
+	// matchVerbatim is a case-sensitive substring, see --case-sensitive.
+	matchVerbatim = "verbatim"
+	// matchExact is an equal name, see --name-exact.
+	matchExact = "exact"
+)
+
+// filterExactName keeps restaurants whose trimmed name equals query,
+// ignoring case unless caseSensitive.
+func filterExactName(restaurants []Restaurant, query string, caseSensitive bool) []Restaurant {
+	query = strings.TrimSpace(query)
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		name := strings.TrimSpace(r.Name)
+		if name == query || (!caseSensitive && strings.EqualFold(name, query)) {
+			filtered = append(filtered, withMatches(r, fieldMatch{"name", matchResult{Matched: true, Reason: matchExact}}))
+		}
+	}
+	return filtered
+}
+
+// filterVerbatim is the --case-sensitive filter: the name, menu and address
+// must contain their query exactly as typed, without folding or fuzzy
+// matching. Empty queries are skipped. With matchAny, one match is enough, like
+// --search; otherwise every query must match.
+func filterVerbatim(restaurants []Restaurant, nameQuery, menuQuery, addressQuery string, matchAny bool) []Restaurant {
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		checks := []struct{ field, text, query string }{
+			{"name", r.Name, nameQuery},
+			{"menu", strings.Join(r.Menu, " "), menuQuery},
+			{"address", r.Address, addressQuery},
+		}
+		matched, missed := false, false
+		var matches []fieldMatch
+		for _, check := range checks {
+			if check.query == "" {
+				continue
+			}
+			result := matchResult{Matched: strings.Contains(check.text, check.query), Reason: matchVerbatim}
+			matches = append(matches, fieldMatch{check.field, result})
+			if result.Matched {
+				matched = true
+			} else {
+				missed = true
+			}
+		}
+		if (matchAny && matched) || (!matchAny && !missed) {
+			filtered = append(filtered, withMatches(r, matches...))
+		}
+	}
+	return filtered
+}
+
+func matchesName(name, queryLower string, maxDistance int) matchResult {
 	lowerName := strings.ToLower(name)
 	if strings.Contains(lowerName, queryLower) {
-		return true
+		return matchResult{Matched: true, Reason: matchSubstring}
 	}
 
 	normName := normalizeToken(lowerName)
 	normQuery := normalizeToken(queryLower)
 
 	if normQuery != "" && strings.Contains(normName, normQuery) {
-		return true
+		return matchResult{Matched: true, Reason: matchNormalized}
 	}
 
-	if dist, ok := safeRankMatchFold(normQuery, normName); ok {
-		return dist >= 0 && dist <= maxDistance
+	if dist, ok := safeRankMatchFold(normQuery, normName); ok && dist >= 0 && dist <= maxDistance {
+		return matchResult{Matched: true, Reason: matchFuzzy, Distance: dist}
 	}
-	return false
+	return matchResult{}
 }
 
 func fuzzThreshold(length int) int {
@@ -565,10 +1808,19 @@ func fuzzThreshold(length int) int {
 	return 3
 }
 
+// normalizeToken lowercases, folds diacritics (kött -> kott) and drops
+// everything that is not a letter or digit.
 func normalizeToken(s string) string {
-	s = strings.ToValidUTF8(s, "")
+	s = norm.NFD.String(strings.ToValidUTF8(s, ""))
 	var b strings.Builder
 	for _, r := range s {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if folded, ok := foldedLetters[unicode.ToLower(r)]; ok {
+			b.WriteString(folded)
+			continue
+		}
 		if unicode.IsLetter(r) || unicode.IsDigit(r) {
 			b.WriteRune(unicode.ToLower(r))
 		}
@@ -576,6 +1828,13 @@ func normalizeToken(s string) string {
 	return b.String()
 }
 
+// foldedLetters covers letters that NFD does not split into base + mark.
+var foldedLetters = map[rune]string{
+	'ø': "o",
+	'æ': "ae",
+	'ß': "ss",
+}
+
 func safeRankMatchFold(query, text string) (int, bool) {
 	query = strings.ToValidUTF8(query, "")
 	text = strings.ToValidUTF8(text, "")
@@ -588,6 +1847,33 @@ func safeRankMatchFold(query, text string) (int, bool) {
 	return dist, true
 }
 
+// filterClosed drops placeholder entries: no menu (or only a closed notice)
+// and a price that is missing or says closed.
+func filterClosed(restaurants []Restaurant) []Restaurant {
+	var open []Restaurant
+	for _, r := range restaurants {
+		if !isClosed(r) {
+			open = append(open, r)
+		}
+	}
+	return open
+}
+
+func isClosed(r Restaurant) bool {
+	for _, line := range r.Menu {
+		if !saysClosed(line) {
+			return false
+		}
+	}
+	return strings.TrimSpace(r.Price) == "" || saysClosed(r.Price)
+}
+
+// saysClosed matches "stängt", "stängd", "semesterstängt", "closed" etc.
+func saysClosed(text string) bool {
+	norm := normalizeToken(text)
+	return strings.Contains(norm, "stang") || strings.Contains(norm, "closed")
+}
+
 func filterByMenu(restaurants []Restaurant, query string) []Restaurant {
 	queryLower := strings.ToLower(query)
 	normQuery := normalizeToken(queryLower)
@@ -596,79 +1882,179 @@ func filterByMenu(restaurants []Restaurant, query string) []Restaurant {
 	var filtered []Restaurant
 	for _, r := range restaurants {
 		menuText := strings.ToLower(strings.Join(r.Menu, " "))
-		if matchesText(menuText, queryLower, normQuery, maxDistance) {
-			filtered = append(filtered, r)
+		if result := matchesText(menuText, queryLower, normQuery, maxDistance); result.Matched {
+			filtered = append(filtered, withMatches(r, fieldMatch{"menu", result}))
+		}
+	}
+	return filtered
+}
+
+// filterByAddress keeps restaurants whose address matches query, fuzzily
+// like --menu.
+func filterByAddress(restaurants []Restaurant, query string) []Restaurant {
+	queryLower := strings.ToLower(query)
+	normQuery := normalizeToken(queryLower)
+	maxDistance := fuzzThreshold(len(normQuery))
+
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		if result := matchesText(strings.ToLower(r.Address), queryLower, normQuery, maxDistance); result.Matched {
+			filtered = append(filtered, withMatches(r, fieldMatch{"address", result}))
 		}
 	}
 	return filtered
 }
 
-func matchesText(text, rawQuery, normQuery string, maxDistance int) bool {
+func matchesText(text, rawQuery, normQuery string, maxDistance int) matchResult {
 	if strings.Contains(text, rawQuery) {
-		return true
+		return matchResult{Matched: true, Reason: matchSubstring}
 	}
 	normText := normalizeToken(text)
 	if normQuery != "" && strings.Contains(normText, normQuery) {
-		return true
+		return matchResult{Matched: true, Reason: matchNormalized}
 	}
 	if normQuery == "" {
-		return false
+		return matchResult{}
 	}
-	if dist, ok := safeRankMatchFold(normQuery, normText); ok {
-		return dist >= 0 && dist <= maxDistance
+	if dist, ok := safeRankMatchFold(normQuery, normText); ok && dist >= 0 && dist <= maxDistance {
+		return matchResult{Matched: true, Reason: matchFuzzy, Distance: dist}
 	}
-	return false
+	return matchResult{}
 }
 
-func filterCombined(restaurants []Restaurant, nameQuery, menuQuery string) []Restaurant {
+// countMenuHits counts occurrences of the normalized query across the menu
+// lines. Fuzzy-only matches count as zero hits.
+func countMenuHits(menu []string, query string, caseSensitive bool) int {
+	if caseSensitive {
+		hits := 0
+		for _, line := range menu {
+			hits += strings.Count(line, query)
+		}
+		return hits
+	}
+	normQuery := normalizeToken(query)
+	if normQuery == "" {
+		return 0
+	}
+	hits := 0
+	for _, line := range menu {
+		hits += strings.Count(normalizeToken(line), normQuery)
+	}
+	return hits
+}
+
+func formatHits(hits int) string {
+	switch hits {
+	case 0:
+		return ""
+	case 1:
+		return " (1 hit)"
+	default:
+		return fmt.Sprintf(" (%d hits)", hits)
+	}
+}
+
+// filterCombined keeps restaurants matching --search in any of name, menu
+// or address.
+func filterCombined(restaurants []Restaurant, nameQuery, menuQuery, addressQuery string) []Restaurant {
 	nameLower := strings.ToLower(strings.TrimSpace(nameQuery))
 	menuLower := strings.ToLower(strings.TrimSpace(menuQuery))
+	addressLower := strings.ToLower(strings.TrimSpace(addressQuery))
 
 	normName := normalizeToken(nameLower)
 	normMenu := normalizeToken(menuLower)
+	normAddress := normalizeToken(addressLower)
 
 	maxName := fuzzThreshold(len(normName))
 	maxMenu := fuzzThreshold(len(normMenu))
+	maxAddress := fuzzThreshold(len(normAddress))
 
 	var filtered []Restaurant
 	for _, r := range restaurants {
-		matchedName := false
-		matchedMenu := false
-
+		var matches []fieldMatch
+		matched := false
+		check := func(field string, result matchResult) {
+			matches = append(matches, fieldMatch{field, result})
+			matched = matched || result.Matched
+		}
 		if nameLower != "" {
-			matchedName = matchesName(r.Name, nameLower, maxName)
+			check("name", matchesName(r.Name, nameLower, maxName))
 		}
 		if menuLower != "" {
 			menuText := strings.ToLower(strings.Join(r.Menu, " "))
-			matchedMenu = matchesText(menuText, menuLower, normMenu, maxMenu)
+			check("menu", matchesText(menuText, menuLower, normMenu, maxMenu))
+		}
+		if addressLower != "" {
+			check("address", matchesText(strings.ToLower(r.Address), addressLower, normAddress, maxAddress))
 		}
 
-		if matchedName || matchedMenu {
-			filtered = append(filtered, r)
+		if matched {
+			filtered = append(filtered, withMatches(r, matches...))
 		}
 	}
 	return filtered
 }
 
+// explainMatch describes why r passed the name, menu, address and
+// --search filters, from the results the filters recorded.
+func explainMatch(r Restaurant) string {
+	parts := make([]string, len(r.Matches))
+	for i, match := range r.Matches {
+		parts[i] = match.Field + " " + formatMatchResult(match.Result)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatMatchResult(result matchResult) string {
+	switch {
+	case !result.Matched:
+		return "no match"
+	case result.Reason == matchFuzzy:
+		return fmt.Sprintf("fuzzy (distance %d)", result.Distance)
+	default:
+		return result.Reason
+	}
+}
+
 func parseDayFlag(input string) (int, bool) {
-	input = strings.TrimSpace(strings.ToLower(input))
+	return parseDayFlagAt(input, time.Now())
+}
+
+// parseDayFlagAt also understands today, tomorrow, yesterday and
+// "next <weekday>", resolved relative to now.
+func parseDayFlagAt(input string, now time.Time) (int, bool) {
+	input = strings.Join(strings.Fields(strings.ToLower(input)), " ")
 	if input == "" {
 		return 0, false
 	}
+	today := weekdayToDay(now.Weekday())
+	switch input {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today%7 + 1, true
+	case "yesterday":
+		return (today+5)%7 + 1, true
+	}
+	if rest, ok := strings.CutPrefix(input, "next "); ok {
+		input = rest
+	}
+	// Swedish names and abbreviations follow the English ones; the single
+	// letters are Swedish, with t for tisdag and to for torsdag.
 	switch input {
-	case "1", "mon", "monday":
+	case "1", "mon", "monday", "m", "mån", "man", "måndag", "mandag":
 		return 1, true
-	case "2", "tue", "tues", "tuesday":
+	case "2", "tue", "tues", "tuesday", "t", "tis", "tisdag":
 		return 2, true
-	case "3", "wed", "weds", "wednesday":
+	case "3", "wed", "weds", "wednesday", "o", "ons", "onsdag":
 		return 3, true
-	case "4", "thu", "thur", "thurs", "thursday":
+	case "4", "thu", "thur", "thurs", "thursday", "to", "tor", "tors", "torsdag":
 		return 4, true
-	case "5", "fri", "friday":
+	case "5", "fri", "friday", "f", "fre", "fredag":
 		return 5, true
-	case "6", "sat", "saturday":
+	case "6", "sat", "saturday", "l", "lör", "lor", "lördag", "lordag":
 		return 6, true
-	case "7", "sun", "sunday":
+	case "7", "sun", "sunday", "s", "sön", "son", "söndag", "sondag":
 		return 7, true
 	default:
 		return 0, false
@@ -717,36 +2103,55 @@ func dayLabel(day int) string {
 	}
 }
 
-func noHitMsg(nameQuery, menuQuery, combinedQuery string) {
-	query := formatQuery(nameQuery, menuQuery, combinedQuery)
+func noHitMsg(nameQuery, menuQuery, combinedQuery, addressQuery string) {
+	query := formatQuery(nameQuery, menuQuery, combinedQuery, addressQuery)
 	if query == "no filters" {
-		fmt.Println("No lunch menus found.")
+		fmt.Fprintln(output, "No lunch menus found.")
 		return
 	}
-	fmt.Printf("No matches for %s.\n", query)
+	fmt.Fprintf(output, "No matches for %s.\n", query)
 }
 
-func printHeader(info SourceInfo, nameQuery, menuQuery, combinedQuery string) {
-	printLine(fmt.Sprintf("Lunch menus — %s", info.Label))
-	printLine(fmt.Sprintf("Query: %s", formatQuery(nameQuery, menuQuery, combinedQuery)))
+func printHeader(info SourceInfo, nameQuery, menuQuery, combinedQuery, addressQuery string, showURL bool) {
+	printStyledLine(fmt.Sprintf("Lunch menus — %s", info.Label), styleBold)
+	printLine(fmt.Sprintf("Query: %s", formatQuery(nameQuery, menuQuery, combinedQuery, addressQuery)))
 	printLine(fmt.Sprintf("Source: %s", formatSourceInfo(info)))
-	fmt.Println()
+	switch {
+	case showURL && info.FinalURL != "":
+		printLine(fmt.Sprintf("URL: %s (redirected from %s)", info.FinalURL, info.URL))
+	case showURL && info.URL != "":
+		printLine(fmt.Sprintf("URL: %s", info.URL))
+	case info.FinalURL != "":
+		printLine(fmt.Sprintf("Redirected to: %s", info.FinalURL))
+	}
+	if info.Warning != "" {
+		printStyledLine(fmt.Sprintf("Note: %s", info.Warning), styleYellow)
+	}
+	fmt.Fprintln(output)
 }
 
-func formatQuery(nameQuery, menuQuery, combinedQuery string) string {
-	if combinedQuery != "" {
-		return fmt.Sprintf("search: %q (name+menu)", combinedQuery)
-	}
+func formatQuery(nameQuery, menuQuery, combinedQuery, addressQuery string) string {
+	var query string
 	switch {
+	case combinedQuery != "":
+		query = fmt.Sprintf("search: %q (name+menu+address)", combinedQuery)
 	case nameQuery != "" && menuQuery != "":
-		return fmt.Sprintf("name: %q, menu: %q", nameQuery, menuQuery)
+		query = fmt.Sprintf("name: %q, menu: %q", nameQuery, menuQuery)
 	case nameQuery != "":
-		return fmt.Sprintf("name: %q", nameQuery)
+		query = fmt.Sprintf("name: %q", nameQuery)
 	case menuQuery != "":
-		return fmt.Sprintf("menu: %q", menuQuery)
-	default:
+		query = fmt.Sprintf("menu: %q", menuQuery)
+	}
+	if addressQuery != "" {
+		if query != "" {
+			query += ", "
+		}
+		query += fmt.Sprintf("address: %q", addressQuery)
+	}
+	if query == "" {
 		return "no filters"
 	}
+	return query
 }
 
 func formatSourceInfo(info SourceInfo) string {
@@ -761,13 +2166,43 @@ func formatSourceInfo(info SourceInfo) string {
 	return fmt.Sprintf("%s (cache updated %s)", source, timestamp)
 }
 
+const (
+	wrapWord = "word"
+	wrapOff  = "off"
+	wrapChar = "char"
+)
+
+// wrapMode is set once from --wrap before any output is printed.
+var wrapMode = wrapWord
+
+// output and outputWidth let text rendering be captured, e.g. into columns.
+// A zero outputWidth means the terminal width.
+var (
+	output      io.Writer = os.Stdout
+	outputWidth int
+)
+
 func printLine(line string) {
-	width := terminalWidth()
-	for _, wrapped := range wrapLine(line, width) {
-		fmt.Println(wrapped)
+	for _, wrapped := range wrappedLines(line) {
+		fmt.Fprintln(output, wrapped)
 	}
 }
 
+// wrappedLines splits line according to wrapMode and the output width.
+func wrappedLines(line string) []string {
+	width := outputWidth
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	switch wrapMode {
+	case wrapOff:
+		return []string{line}
+	case wrapChar:
+		return hardWrapLine(line, width)
+	}
+	return wrapLine(line, width)
+}
+
 func terminalWidth() int {
 	if value := strings.TrimSpace(os.Getenv("COLUMNS")); value != "" {
 		if n, err := strconv.Atoi(value); err == nil && n >= 40 {
@@ -806,6 +2241,31 @@ func wrapLine(line string, width int) []string {
 	return lines
 }
 
+// hardWrapLine splits at exactly width runes, even mid-word, keeping the
+// indent on continuation lines.
+func hardWrapLine(line string, width int) []string {
+	runes := []rune(line)
+	if width <= 0 || len(runes) <= width {
+		return []string{line}
+	}
+	indent := leadingSpaces(line)
+	if indent >= width/2 {
+		indent = 0
+	}
+	prefix := strings.Repeat(" ", indent)
+	lines := []string{string(runes[:width])}
+	runes = runes[width:]
+	for len(runes) > 0 {
+		n := width - indent
+		if n > len(runes) {
+			n = len(runes)
+		}
+		lines = append(lines, prefix+string(runes[:n]))
+		runes = runes[n:]
+	}
+	return lines
+}
+
 func leadingSpaces(line string) int {
 	for i, r := range line {
 		if r != ' ' {
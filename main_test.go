@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseDayFlagAt(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2026, time.October, 14, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		input string
+		want  int
+		ok    bool
+	}{
+		{"m", 1, true},
+		{"t", 2, true},
+		{"o", 3, true},
+		{"to", 4, true},
+		{"f", 5, true},
+		{"l", 6, true},
+		{"s", 7, true},
+		{"mån", 1, true},
+		{"man", 1, true},
+		{"tis", 2, true},
+		{"ons", 3, true},
+		{"tor", 4, true},
+		{"tors", 4, true},
+		{"fre", 5, true},
+		{"lör", 6, true},
+		{"lor", 6, true},
+		{"sön", 7, true},
+		{"son", 7, true},
+		{"måndag", 1, true},
+		{"mandag", 1, true},
+		{"tisdag", 2, true},
+		{"onsdag", 3, true},
+		{"torsdag", 4, true},
+		{"fredag", 5, true},
+		{"lördag", 6, true},
+		{"lordag", 6, true},
+		{"söndag", 7, true},
+		{"sondag", 7, true},
+		{"Måndag", 1, true},
+		{"  FREDAG ", 5, true},
+		{"monday", 1, true},
+		{"thurs", 4, true},
+		{"sun", 7, true},
+		{"3", 3, true},
+		{"today", 3, true},
+		{"tomorrow", 4, true},
+		{"yesterday", 2, true},
+		{"next fre", 5, true},
+		{"next  tisdag", 2, true},
+		{"", 0, false},
+		{"ma", 0, false},
+		{"tu", 0, false},
+		{"th", 0, false},
+		{"fr", 0, false},
+		{"sa", 0, false},
+		{"su", 0, false},
+		{"lö", 0, false},
+		{"sö", 0, false},
+		{"0", 0, false},
+		{"8", 0, false},
+		{"next", 0, false},
+		{"next today", 0, false},
+		{"måndagar", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDayFlagAt(tt.input, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseDayFlagAt(%q) = %d, %v; want %d, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeToken(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"kött", "kott"},
+		{"kott", "kott"},
+		{"KÖTT", "kott"},
+		{"\u00e0", "a"},
+		{"a\u0300", "a"},
+		{"a", "a"},
+		{"Crème brûlée", "cremebrulee"},
+		{"Smørrebrød", "smorrebrod"},
+		{"SMØRREBRØD", "smorrebrod"},
+		{"Blåbærsuppe", "blabaersuppe"},
+		{"Weißwurst", "weisswurst"},
+		{"räksmörgås!", "raksmorgas"},
+		{"pasta, 95:-", "pasta95"},
+		{"\u00e5g\u00e5rden", "agarden"},
+		{"a\u030aga\u030arden", "agarden"},
+		{"\xffsoppa", "soppa"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeToken(tt.input); got != tt.want {
+			t.Errorf("normalizeToken(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if normalizeToken("kött") != normalizeToken("kott") {
+		t.Error("kött and kott normalize differently")
+	}
+}
+
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, areaCacheName(defaultCacheNameTemplate, AreaConfig{City: "goteborg", Area: "garda_161"}, 5))
+	pages := [][]byte{
+		bytes.Repeat([]byte("a"), 256<<10),
+		bytes.Repeat([]byte("b"), 256<<10),
+	}
+
+	var writers sync.WaitGroup
+	for _, page := range pages {
+		writers.Add(1)
+		go func(page []byte) {
+			defer writers.Done()
+			for i := 0; i < 50; i++ {
+				if err := writeFileAtomic(path, page, 0o644); err != nil {
+					t.Errorf("writeFileAtomic: %v", err)
+					return
+				}
+			}
+		}(page)
+	}
+	done := make(chan struct{})
+	go func() {
+		writers.Wait()
+		close(done)
+	}()
+
+	for reads := 0; ; reads++ {
+		select {
+		case <-done:
+			t.Logf("%d reads", reads)
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("%d files left in the cache directory, want only the page", len(entries))
+			}
+			return
+		default:
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !bytes.Equal(data, pages[0]) && !bytes.Equal(data, pages[1]) {
+			t.Fatalf("read a partial page of %d bytes", len(data))
+		}
+	}
+}
+
+func TestFetchHTMLTranscodesLatin1(t *testing.T) {
+	// "Räksmörgås på Gårda" in ISO-8859-1.
+	latin1 := []byte("<p>R\xe4ksm\xf6rg\xe5s p\xe5 G\xe5rda</p>")
+	tests := []struct {
+		name        string
+		contentType string
+		page        []byte
+	}{
+		{"header", "text/html; charset=ISO-8859-1", latin1},
+		{"meta", "text/html", append([]byte(`<html><head><meta charset="iso-8859-1"></head><body>`), latin1...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(tt.page)
+			}))
+			defer server.Close()
+
+			resp, err := fetchHTML(context.Background(), server.URL, Options{})
+			if err != nil {
+				t.Fatalf("fetchHTML: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(body, []byte("<p>Räksmörgås på Gårda</p>")) {
+				t.Errorf("body = %q, want åäö as UTF-8", body)
+			}
+		})
+	}
+}
+
+// BenchmarkFetchFiveAreas fetches five area pages concurrently per
+// iteration and reports how many connections the server accepted. "shared"
+// uses httpClient as is; "no-reuse" closes every connection after one
+// request, as a fresh client per fetch would.
+func BenchmarkFetchFiveAreas(b *testing.B) {
+	page := bytes.Repeat([]byte("<p>Köttbullar med potatis</p>"), 200)
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	transport := httpClient.Transport
+	defer func() { httpClient.Transport = transport }()
+	for _, bench := range []struct {
+		name      string
+		transport http.RoundTripper
+	}{
+		{"shared", transport},
+		{"no-reuse", &http.Transport{DisableKeepAlives: true}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			httpClient.Transport = bench.transport
+			httpClient.CloseIdleConnections()
+			conns.Store(0)
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				for area := 1; area <= 5; area++ {
+					wg.Add(1)
+					go func(area int) {
+						defer wg.Done()
+						resp, err := fetchHTML(context.Background(), fmt.Sprintf("%s/goteborg/area/%d", server.URL, area), Options{})
+						if err != nil {
+							b.Error(err)
+							return
+						}
+						io.Copy(io.Discard, resp.Body)
+						resp.Body.Close()
+					}(area)
+				}
+				wg.Wait()
+			}
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestExplainMatchFollowsFilters(t *testing.T) {
+	restaurants := []Restaurant{
+		{Name: "Ask Bo", Address: "Gårdavägen 1", Menu: []string{"Pytt i panna"}},
+		{Name: "Koka Bistro", Address: "Kungsgatan 12", Menu: []string{"Köttbullar med potatis"}},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]string
+	}{
+		{
+			// "Åsö" is 5 bytes but 3 letters once folded; the filter's
+			// threshold decides, so --explain must not say "no match".
+			name: "fuzzy name with diacritics",
+			opts: Options{Name: "Åsö"},
+			want: map[string]string{"Ask Bo": "name fuzzy (distance 2)"},
+		},
+		{
+			name: "menu",
+			opts: Options{Menu: "kottbullar"},
+			want: map[string]string{"Koka Bistro": "menu normalized"},
+		},
+		{
+			name: "search",
+			opts: Options{Search: "gårdavägen"},
+			want: map[string]string{"Ask Bo": "name no match, menu no match, address substring"},
+		},
+		{
+			name: "name and address",
+			opts: Options{Name: "koka", Address: "kungsgatan"},
+			want: map[string]string{"Koka Bistro": "name substring, address substring"},
+		},
+		{
+			name: "case-sensitive search",
+			opts: Options{Search: "Pytt", CaseSensitive: true},
+			want: map[string]string{"Ask Bo": "name no match, menu verbatim, address no match"},
+		},
+		{
+			name: "exact name",
+			opts: Options{Name: "koka bistro", NameExact: true},
+			want: map[string]string{"Koka Bistro": "name exact"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nameQuery, menuQuery := effectiveQueries(tt.opts)
+			got := map[string]string{}
+			for _, r := range applyFilters(restaurants, tt.opts, nameQuery, menuQuery) {
+				got[r.Name] = explainMatch(r)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("explanations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	for _, r := range restaurants {
+		if len(r.Matches) != 0 {
+			t.Errorf("filters changed the input restaurant %s", r.Name)
+		}
+	}
+}
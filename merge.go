@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// parseDayRange accepts "mon-fri", "1-5" or a list like "mon,wed,fri".
+// Ranges may wrap around the week, e.g. "sat-mon".
+func parseDayRange(input string) ([]int, error) {
+	var days []int
+	seen := map[int]bool{}
+	add := func(day int) {
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := parseDayFlag(from)
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q in %q (use e.g. mon-fri)", from, input)
+		}
+		if !isRange {
+			add(start)
+			continue
+		}
+		end, ok := parseDayFlag(to)
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q in %q (use e.g. mon-fri)", to, input)
+		}
+		for day := start; ; day = day%7 + 1 {
+			add(day)
+			if day == end {
+				break
+			}
+		}
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("invalid day range %q (use e.g. mon-fri)", input)
+	}
+	return days, nil
+}
+
+// loadMergedDays fetches every day in opts.MergeDays for one area, filters
+// each day and unions the results by restaurant name. Menu lines are deduped
+// and each restaurant records the days it was seen on.
+func loadMergedDays(ctx context.Context, opts Options, area AreaConfig, nameQuery, menuQuery string) ([]Restaurant, SourceInfo, error) {
+	var merged []Restaurant
+	index := map[string]int{}
+	sources := map[string]bool{}
+
+	for _, day := range opts.MergeDays {
+		dayOpts := opts
+		dayOpts.Day = day
+		restaurants, info, err := loadRestaurants(ctx, dayOpts, area)
+		if err != nil {
+			return nil, SourceInfo{}, err
+		}
+		sources[info.Source] = true
+
+		for _, r := range applyFilters(restaurants, dayOpts, nameQuery, menuQuery) {
+			key := strings.ToLower(strings.TrimSpace(r.Name))
+			i, ok := index[key]
+			if !ok {
+				r.Menu = append([]string(nil), r.Menu...)
+				r.Days = []string{dayLabel(day)}
+				index[key] = len(merged)
+				merged = append(merged, r)
+				continue
+			}
+			existing := &merged[i]
+			existing.Days = append(existing.Days, dayLabel(day))
+			for _, line := range r.Menu {
+				if !slices.Contains(existing.Menu, line) {
+					existing.Menu = append(existing.Menu, line)
+				}
+			}
+		}
+	}
+
+	labels := make([]string, len(opts.MergeDays))
+	for i, day := range opts.MergeDays {
+		labels[i] = dayLabel(day)
+	}
+	source := "cache"
+	if sources["live"] {
+		source = "live"
+		if sources["cache"] {
+			source = "live+cache"
+		}
+	}
+	info := SourceInfo{
+		Label:  fmt.Sprintf("%s (days %s)", areaLabel(area), strings.Join(labels, ", ")),
+		Source: source,
+	}
+	return merged, info, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastSuccessMetric keeps its value across runs that had failures.
+const lastSuccessMetric = "kvartersmenyn_last_success_timestamp_seconds"
+
+// writeMetricsFile writes the run's stats in the Prometheus textfile format
+// for node_exporter's textfile collector. The file is replaced atomically so
+// the collector never reads half of it.
+func writeMetricsFile(path string, stats runStats, took time.Duration, failed int, now time.Time) error {
+	var buf bytes.Buffer
+	gauge := func(name, help string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	gauge("kvartersmenyn_run_duration_seconds", "Duration of the last run.")
+	fmt.Fprintf(&buf, "kvartersmenyn_run_duration_seconds %s\n", formatMetric(took.Seconds()))
+	gauge("kvartersmenyn_areas", "Areas (and days) loaded in the last run.")
+	fmt.Fprintf(&buf, "kvartersmenyn_areas %d\n", stats.Areas)
+	gauge("kvartersmenyn_areas_failed", "Areas that failed in the last run.")
+	fmt.Fprintf(&buf, "kvartersmenyn_areas_failed %d\n", failed)
+	gauge("kvartersmenyn_cache_hits", "Pages read from cache in the last run.")
+	fmt.Fprintf(&buf, "kvartersmenyn_cache_hits %d\n", stats.CacheHits)
+	gauge("kvartersmenyn_cache_misses", "Pages fetched live in the last run.")
+	fmt.Fprintf(&buf, "kvartersmenyn_cache_misses %d\n", stats.CacheMisses)
+
+	gauge("kvartersmenyn_fetch_duration_seconds", "Time to load an area's menu in the last run.")
+	for _, t := range stats.Timings {
+		fmt.Fprintf(&buf, "kvartersmenyn_fetch_duration_seconds{%s} %s\n", metricLabels(t), formatMetric(float64(t.MS)/1000))
+	}
+	gauge("kvartersmenyn_results", "Restaurants matched per area in the last run.")
+	for _, t := range stats.Timings {
+		fmt.Fprintf(&buf, "kvartersmenyn_results{%s} %d\n", metricLabels(t), t.Restaurants)
+	}
+	gauge("kvartersmenyn_area_up", "Whether the area loaded (1) or failed (0) in the last run.")
+	for _, t := range stats.Timings {
+		up := 1
+		if t.Failed {
+			up = 0
+		}
+		fmt.Fprintf(&buf, "kvartersmenyn_area_up{%s} %d\n", metricLabels(t), up)
+	}
+
+	lastSuccess := previousLastSuccess(path)
+	if failed == 0 {
+		lastSuccess = strconv.FormatInt(now.Unix(), 10)
+	}
+	if lastSuccess != "" {
+		gauge(lastSuccessMetric, "Unix time of the last run in which no area failed.")
+		fmt.Fprintf(&buf, "%s %s\n", lastSuccessMetric, lastSuccess)
+	}
+
+	if err := writeFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("could not write metrics file (%s): %w", path, err)
+	}
+	return nil
+}
+
+// previousLastSuccess returns the last-success value of an earlier metrics
+// file at path, or "" when there is none.
+func previousLastSuccess(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), lastSuccessMetric+" "); ok {
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+func metricLabels(t areaTiming) string {
+	return fmt.Sprintf(`city=%s,area=%s,day=%s`, metricLabelValue(t.city), metricLabelValue(t.slug), metricLabelValue(t.day))
+}
+
+// metricLabelValue quotes a label value, escaping backslashes, quotes and
+// newlines as the text format requires.
+func metricLabelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// writeRunMetrics writes --metrics-file when it is set; failing to write it
+// does not fail the run.
+func writeRunMetrics(opts Options, stats runStats, took time.Duration, failed int) {
+	if opts.MetricsFile == "" {
+		return
+	}
+	if err := writeMetricsFile(opts.MetricsFile, stats, took, failed, time.Now()); err != nil {
+		log.Print(err)
+	}
+}
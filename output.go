@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatICal  = "ical"
+	formatDebug = "debug"
+	formatVCard = "vcard"
+)
+
+// outputFormats lists the values accepted by --format and output_format.
+var outputFormats = []string{formatText, formatJSON, formatICal, formatDebug, formatVCard}
+
+// parseFormat accepts an empty value as text.
+func parseFormat(input string) (string, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return formatText, true
+	}
+	for _, format := range outputFormats {
+		if input == format {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// areaResult is the filtered outcome for one area and day.
+type areaResult struct {
+	Area AreaConfig
+	Day  int
+	// Days is set instead of Day when several days were merged.
+	Days        []int
+	Info        SourceInfo
+	Restaurants []Restaurant
+}
+
+// resultLabel is the area's label with the day, e.g.
+// "goteborg/garda_161 (day fri)".
+func resultLabel(result areaResult) string {
+	if len(result.Days) > 0 || dayLabel(result.Day) == "" {
+		return result.Info.Label
+	}
+	return fmt.Sprintf("%s (day %s)", result.Info.Label, dayLabel(result.Day))
+}
+
+// dayHeading names day with its date for headers, e.g. "Friday 14 Mar".
+func dayHeading(day int, now time.Time) string {
+	return dayDate(day, now).Format("Monday 2 Jan")
+}
+
+// dayDate is the date day refers to, counting forward from now as
+// parseDayFlagAt does: today, or its next occurrence. Only yesterday's
+// weekday lies in the past, as with --day yesterday. On a Sunday, day 1 is
+// tomorrow, not six days ago.
+func dayDate(day int, now time.Time) time.Time {
+	ahead := (day - weekdayToDay(now.Weekday()) + 7) % 7
+	if ahead == 6 {
+		ahead = -1
+	}
+	return now.AddDate(0, 0, ahead)
+}
+
+func printAreaText(result areaResult, opts Options, nameQuery, menuQuery, combinedQuery string) {
+	printAreaSeparator()
+	addressQuery := strings.TrimSpace(opts.Address)
+	info := result.Info
+	if len(result.Days) == 0 && dayLabel(result.Day) != "" {
+		info.Label = fmt.Sprintf("%s (%s)", info.Label, dayHeading(result.Day, time.Now()))
+	}
+	printHeader(info, nameQuery, menuQuery, combinedQuery, addressQuery, opts.ShowURL)
+	if len(result.Restaurants) == 0 {
+		noHitMsg(nameQuery, menuQuery, combinedQuery, addressQuery)
+		return
+	}
+
+	if opts.Buckets {
+		for _, bucket := range bucketRestaurants(result.Restaurants, opts.PriceBuckets) {
+			printLine(fmt.Sprintf("== %s (%d) ==", bucket.Label, len(bucket.Restaurants)))
+			fmt.Fprintln(output)
+			for _, r := range bucket.Restaurants {
+				printRestaurant(r, opts, menuQuery)
+			}
+		}
+		return
+	}
+	for _, r := range result.Restaurants {
+		printRestaurant(r, opts, menuQuery)
+	}
+}
+
+const (
+	separatorRule  = "rule"
+	separatorBlank = "blank"
+	separatorNone  = "none"
+)
+
+// areaSeparator is set once from --separator before any output is printed.
+var areaSeparator = separatorRule
+
+// lastAreaOutput is the writer the previous area block went to. Blocks are
+// only separated within one writer, so a captured column or a file from
+// --output-dir does not start with a separator.
+var lastAreaOutput io.Writer
+
+// printAreaSeparator prints the --separator line when an area block was
+// already printed to the current output.
+func printAreaSeparator() {
+	previous := lastAreaOutput
+	lastAreaOutput = output
+	if previous != output {
+		return
+	}
+	switch areaSeparator {
+	case separatorRule:
+		width := outputWidth
+		if width <= 0 {
+			width = terminalWidth()
+		}
+		fmt.Fprintln(output, strings.Repeat("─", width))
+		fmt.Fprintln(output)
+	case separatorBlank:
+		fmt.Fprintln(output)
+	}
+}
+
+// printAreaDebug dumps every Restaurant field as parsed, quoting strings so
+// stray whitespace shows and marking empty fields, for diagnosing the
+// scraper rather than for reading menus.
+func printAreaDebug(result areaResult) {
+	fmt.Fprintf(output, "== %s source=%s restaurants=%d ==\n", resultLabel(result), result.Info.Source, len(result.Restaurants))
+	for i, r := range result.Restaurants {
+		fmt.Fprintf(output, "Restaurant[%d]{\n", i)
+		v := reflect.ValueOf(r)
+		for j := 0; j < v.NumField(); j++ {
+			name := v.Type().Field(j).Name
+			field := v.Field(j)
+			switch field.Kind() {
+			case reflect.String:
+				if field.Len() == 0 {
+					fmt.Fprintf(output, "  %s: <empty>\n", name)
+				} else {
+					fmt.Fprintf(output, "  %s: %q\n", name, field.String())
+				}
+			case reflect.Slice:
+				if field.Len() == 0 {
+					fmt.Fprintf(output, "  %s: <empty>\n", name)
+				}
+				for k := 0; k < field.Len(); k++ {
+					if field.Index(k).Kind() == reflect.Struct {
+						fmt.Fprintf(output, "  %s[%d]: %+v\n", name, k, field.Index(k).Interface())
+						continue
+					}
+					fmt.Fprintf(output, "  %s[%d]: %q\n", name, k, field.Index(k).Interface())
+				}
+			default:
+				fmt.Fprintf(output, "  %s: %+v\n", name, field.Interface())
+			}
+		}
+		fmt.Fprintln(output, "}")
+	}
+	fmt.Fprintln(output)
+}
+
+// limitPerCity truncates results so each city shows at most limit
+// restaurants in total, spending the budget on its areas in order. It
+// returns how many restaurants were cut per city.
+func limitPerCity(results []areaResult, limit int) map[string]int {
+	shown := map[string]int{}
+	hidden := map[string]int{}
+	for i := range results {
+		city := results[i].Area.City
+		room := max(limit-shown[city], 0)
+		if n := len(results[i].Restaurants); n > room {
+			hidden[city] += n - room
+			results[i].Restaurants = results[i].Restaurants[:room]
+		}
+		shown[city] += len(results[i].Restaurants)
+	}
+	return hidden
+}
+
+// lastOfCity reports whether results[i] is the last area of its city.
+func lastOfCity(results []areaResult, i int) bool {
+	for _, later := range results[i+1:] {
+		if later.Area.City == results[i].Area.City {
+			return false
+		}
+	}
+	return true
+}
+
+type jsonArea struct {
+	City         string       `json:"city"`
+	Area         string       `json:"area,omitempty"`
+	Day          string       `json:"day,omitempty"`
+	Days         []string     `json:"days,omitempty"`
+	Source       string       `json:"source"`
+	CacheUpdated *time.Time   `json:"cache_updated,omitempty"`
+	Warning      string       `json:"warning,omitempty"`
+	URL          string       `json:"url,omitempty"`
+	FinalURL     string       `json:"final_url,omitempty"`
+	Restaurants  []Restaurant `json:"restaurants"`
+}
+
+func toJSONArea(result areaResult) jsonArea {
+	out := jsonArea{
+		City:        result.Area.City,
+		Area:        result.Area.Area,
+		Day:         dayLabel(result.Day),
+		Source:      result.Info.Source,
+		Warning:     result.Info.Warning,
+		URL:         result.Info.URL,
+		FinalURL:    result.Info.FinalURL,
+		Restaurants: result.Restaurants,
+	}
+	if len(result.Days) > 0 {
+		out.Day = ""
+		for _, day := range result.Days {
+			out.Days = append(out.Days, dayLabel(day))
+		}
+	}
+	if !result.Info.CacheUpdated.IsZero() {
+		updated := result.Info.CacheUpdated
+		out.CacheUpdated = &updated
+	}
+	if out.Restaurants == nil {
+		out.Restaurants = []Restaurant{}
+	}
+	return out
+}
+
+// writeJSON prints all areas as one indented JSON array.
+func writeJSON(w io.Writer, results []areaResult) error {
+	areas := make([]jsonArea, 0, len(results))
+	for _, result := range results {
+		areas = append(areas, toJSONArea(result))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(areas)
+}
+
+// jsonStreamSummary is the last line of --json-stream output, and the
+// summary next to the areas with --summary-json.
+type jsonStreamSummary struct {
+	Results     int      `json:"results"`
+	Restaurants int      `json:"restaurants"`
+	Failed      []string `json:"failed"`
+	Interrupted bool     `json:"interrupted"`
+	// runStats is only set with --summary-json.
+	*runStats
+}
+
+// writeJSONWithSummary prints all areas and the run summary as one
+// indented object, {"areas": [...], "summary": {...}}.
+func writeJSONWithSummary(w io.Writer, results []areaResult, summary jsonStreamSummary) error {
+	areas := make([]jsonArea, 0, len(results))
+	for _, result := range results {
+		areas = append(areas, toJSONArea(result))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Areas   []jsonArea        `json:"areas"`
+		Summary jsonStreamSummary `json:"summary"`
+	}{areas, summary})
+}
+
+// writeJSONLine prints v as one line of NDJSON.
+func writeJSONLine(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// minCompareColumn is the narrowest column --compare will lay out; below it
+// the two areas are printed one after the other.
+const minCompareColumn = 36
+
+// printCompare prints two areas side by side within the terminal width.
+func printCompare(left, right areaResult, opts Options, nameQuery, menuQuery, combinedQuery string) {
+	column := (terminalWidth() - 3) / 2
+	if column < minCompareColumn {
+		printAreaText(left, opts, nameQuery, menuQuery, combinedQuery)
+		printAreaText(right, opts, nameQuery, menuQuery, combinedQuery)
+		return
+	}
+
+	render := func(result areaResult) []string {
+		return captureLines(column, func() {
+			printAreaText(result, opts, nameQuery, menuQuery, combinedQuery)
+		})
+	}
+	leftLines, rightLines := render(left), render(right)
+	for i := 0; i < len(leftLines) || i < len(rightLines); i++ {
+		var l, r string
+		if i < len(leftLines) {
+			l = leftLines[i]
+		}
+		if i < len(rightLines) {
+			r = rightLines[i]
+		}
+		fmt.Fprintln(output, strings.TrimRight(fmt.Sprintf("%-*s │ %s", column, l, r), " "))
+	}
+}
+
+// captureLines runs render with output redirected to a buffer of the given
+// width and returns the printed lines. Lines are always word-wrapped so they
+// fit the column, and never colored.
+func captureLines(width int, render func()) []string {
+	var buf bytes.Buffer
+	prevOutput, prevWidth, prevWrap, prevColor := output, outputWidth, wrapMode, colorEnabled
+	// Escape codes would throw off the column padding.
+	output, outputWidth, colorEnabled = &buf, width, false
+	if wrapMode == wrapOff {
+		wrapMode = wrapWord
+	}
+	defer func() {
+		output, outputWidth, wrapMode, colorEnabled = prevOutput, prevWidth, prevWrap, prevColor
+	}()
+
+	render()
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
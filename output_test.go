@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayHeadingOnSunday(t *testing.T) {
+	sunday := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"today", "Sunday 18 Oct"},
+		{"tomorrow", "Monday 19 Oct"},
+		{"next monday", "Monday 19 Oct"},
+		{"mon", "Monday 19 Oct"},
+		{"fri", "Friday 23 Oct"},
+		{"yesterday", "Saturday 17 Oct"},
+	}
+	for _, tt := range tests {
+		day, ok := parseDayFlagAt(tt.input, sunday)
+		if !ok {
+			t.Fatalf("parseDayFlagAt(%q) failed", tt.input)
+		}
+		if got := dayHeading(day, sunday); got != tt.want {
+			t.Errorf("dayHeading(%q on a Sunday) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDayHeadingMidweek(t *testing.T) {
+	wednesday := time.Date(2026, time.October, 14, 12, 0, 0, 0, time.Local)
+	for day, want := range map[int]string{
+		2: "Tuesday 13 Oct",
+		3: "Wednesday 14 Oct",
+		4: "Thursday 15 Oct",
+		1: "Monday 19 Oct",
+	} {
+		if got := dayHeading(day, wednesday); got != want {
+			t.Errorf("dayHeading(%d on a Wednesday) = %q, want %q", day, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// outputDirExtensions are the file extensions --output-dir uses per format.
+var outputDirExtensions = map[string]string{
+	formatText:  ".txt",
+	formatJSON:  ".json",
+	formatICal:  ".ics",
+	formatVCard: ".vcf",
+	formatDebug: ".txt",
+}
+
+// writeAreaFile renders one area in opts.Format to its own file in dir,
+// named like the cached page, and returns the file's path. Text is written
+// without color or wrapping.
+func writeAreaFile(dir string, result areaResult, opts Options, nameQuery, menuQuery, combinedQuery string, now time.Time) (string, error) {
+	var buf bytes.Buffer
+	var err error
+	switch opts.Format {
+	case formatJSON:
+		err = writeJSON(&buf, []areaResult{result})
+	case formatICal:
+		err = writeICal(&buf, []areaResult{result}, now)
+	case formatVCard:
+		err = writeVCard(&buf, []areaResult{result})
+	default:
+		prevOutput, prevWrap, prevColor := output, wrapMode, colorEnabled
+		output, wrapMode, colorEnabled = &buf, wrapOff, false
+		if opts.Format == formatDebug {
+			printAreaDebug(result)
+		} else {
+			printAreaText(result, opts, nameQuery, menuQuery, combinedQuery)
+		}
+		output, wrapMode, colorEnabled = prevOutput, prevWrap, prevColor
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("could not create output directory (%s): %w", dir, err)
+	}
+	name := strings.TrimSuffix(areaCacheName(defaultCacheNameTemplate, result.Area, result.Day), ".html")
+	path := filepath.Join(dir, name+outputDirExtensions[opts.Format])
+	if err := writeFileAtomic(path, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("could not write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bundledPostcodes maps postcode prefixes to the areas they cover. Longer
+// prefixes win, and the postcodes config key can add or override entries.
+var bundledPostcodes = map[string][]string{
+	"412": {"goteborg/garda_161", "goteborg/johanneberg_43"},
+	"114": {"stockholm/ostermalm_42"},
+}
+
+// lookupPostcode returns the areas for the longest matching prefix of code,
+// optionally limited to cities.
+func lookupPostcode(code string, cities []string, extra map[string][]string) ([]AreaConfig, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != 5 || !allDigits(code) {
+		return nil, fmt.Errorf("invalid postcode %q (use five digits, e.g. 41263)", code)
+	}
+
+	for n := len(code); n > 0; n-- {
+		labels, ok := extra[code[:n]]
+		if !ok {
+			labels, ok = bundledPostcodes[code[:n]]
+		}
+		if !ok {
+			continue
+		}
+		var areas []AreaConfig
+		for _, label := range labels {
+			areaCity, areaSlug, found := strings.Cut(label, "/")
+			if !found || areaCity == "" || areaSlug == "" {
+				return nil, fmt.Errorf("invalid postcode area %q (use city/area)", label)
+			}
+			if len(cities) > 0 && !slices.ContainsFunc(cities, func(city string) bool { return strings.EqualFold(city, areaCity) }) {
+				continue
+			}
+			areas = append(areas, AreaConfig{City: areaCity, Area: areaSlug})
+		}
+		if len(areas) > 0 {
+			return areas, nil
+		}
+	}
+	return nil, fmt.Errorf("no known areas for postcode %s; pass --area directly or add it under postcodes in config", code)
+}
+
+// pickPostcodeAreas asks the user to choose among several candidate areas.
+// Without a terminal it lists them and returns an error instead.
+func pickPostcodeAreas(code string, candidates []AreaConfig) ([]AreaConfig, error) {
+	if len(candidates) == 1 {
+		return candidates, nil
+	}
+
+	var list strings.Builder
+	for i, area := range candidates {
+		fmt.Fprintf(&list, "  %d) %s\n", i+1, areaLabel(area))
+	}
+	if !isInteractive() {
+		return nil, fmt.Errorf("postcode %s matches several areas, pass one with --area:\n%s", code, strings.TrimRight(list.String(), "\n"))
+	}
+
+	fmt.Printf("Postcode %s matches several areas:\n%s", code, list.String())
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Pick one or more (e.g. 1,2; empty for all): ")
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err != nil {
+				return nil, errors.New("no area picked")
+			}
+			return candidates, nil
+		}
+		picked, ok := parsePicks(line, candidates)
+		if ok {
+			return picked, nil
+		}
+		fmt.Println("Please enter numbers from the list.")
+	}
+}
+
+func parsePicks(input string, candidates []AreaConfig) ([]AreaConfig, bool) {
+	var indices []int
+	for _, part := range strings.Split(input, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > len(candidates) {
+			return nil, false
+		}
+		indices = append(indices, n-1)
+	}
+	sort.Ints(indices)
+	var picked []AreaConfig
+	for i, idx := range indices {
+		if i > 0 && indices[i-1] == idx {
+			continue
+		}
+		picked = append(picked, candidates[idx])
+	}
+	return picked, true
+}
+
+// isInteractive reports whether stdin is a terminal.
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
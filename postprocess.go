@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// postProcessTimeout bounds one run of the --post-process command.
+const postProcessTimeout = 30 * time.Second
+
+// postProcess pipes one area's filtered result to command and returns the
+// restaurants it prints. The command reads a single object shaped like an
+// element of --format json on stdin and must print a JSON array of
+// restaurants in the same shape on stdout. On any failure the unmodified
+// restaurants are returned with a warning.
+func postProcess(ctx context.Context, command string, result areaResult) []Restaurant {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return result.Restaurants
+	}
+	input, err := json.Marshal(toJSONArea(result))
+	if err != nil {
+		log.Printf("post-process skipped for %s: %v", areaLabel(result.Area), err)
+		return result.Restaurants
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, postProcessTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		log.Printf("post-process failed for %s, using unmodified data: %v", areaLabel(result.Area), err)
+		return result.Restaurants
+	}
+
+	var restaurants []Restaurant
+	if err := json.Unmarshal(output, &restaurants); err != nil {
+		log.Printf("post-process for %s printed invalid JSON, using unmodified data: %v", areaLabel(result.Area), err)
+		return result.Restaurants
+	}
+	return restaurants
+}
+
+// formatExtra renders a post-processor's extra value on one line.
+func formatExtra(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	currencySEK = "SEK"
+	currencyEUR = "EUR"
+)
+
+// defaultEURRate is used when no eur_rate is configured (SEK per EUR).
+const defaultEURRate = 11.5
+
+// parsePrice extracts the amount from a price string and detects its
+// currency. A range like "110–145 kr" yields both bounds; a single amount
+// yields it as both. Prices without a currency marker report an empty
+// currency. Amounts are read the Swedish way too, see leadingAmount.
+func parsePrice(raw string) (float64, float64, string, bool) {
+	text := strings.ToLower(normalizeSpaces(raw))
+	if text == "" {
+		return 0, 0, "", false
+	}
+
+	low, rest, ok := leadingAmount(text)
+	if !ok {
+		return 0, 0, "", false
+	}
+	high := low
+	// A dash right after the amount followed by another amount is a range;
+	// "129:-" and "95 -" are not.
+	rest = strings.TrimLeft(rest, " ")
+	for _, dash := range []string{"-", "–", "—"} {
+		if after, found := strings.CutPrefix(rest, dash); found {
+			after = strings.TrimLeft(after, " ")
+			if after != "" && after[0] >= '0' && after[0] <= '9' {
+				if upper, _, ok := leadingAmount(after); ok && upper > low {
+					high = upper
+				}
+			}
+			break
+		}
+	}
+	return low, high, detectCurrency(text), true
+}
+
+// leadingAmount parses the first number in text and returns the text after it.
+// Besides "129" and "129.50" it understands Swedish notation: a decimal
+// comma ("12,50", "129,-"), kronor and öre split by a colon ("129:50") and
+// the dash for no öre ("129:-", where the ":-" is left in the rest).
+func leadingAmount(text string) (float64, string, bool) {
+	start := strings.IndexAny(text, "0123456789")
+	if start < 0 {
+		return 0, "", false
+	}
+	end := start
+	for end < len(text) && (isDigit(text[end]) || text[end] == '.' || text[end] == ',') {
+		end++
+	}
+	number := strings.TrimRight(strings.ReplaceAll(text[start:end], ",", "."), ".")
+	if !strings.Contains(number, ".") && end+2 < len(text) && text[end] == ':' && isDigit(text[end+1]) && isDigit(text[end+2]) &&
+		(end+3 == len(text) || !isDigit(text[end+3])) {
+		number += "." + text[end+1:end+3]
+		end += 3
+	}
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount <= 0 {
+		return 0, "", false
+	}
+	return amount, text[end:], true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func detectCurrency(text string) string {
+	switch {
+	case strings.Contains(text, "€") || strings.Contains(text, "eur"):
+		return currencyEUR
+	case strings.Contains(text, "kr") || strings.Contains(text, "sek") || strings.Contains(text, ":-") || strings.Contains(text, ",-"):
+		return currencySEK
+	default:
+		return ""
+	}
+}
+
+func parseCurrency(input string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(input)) {
+	case "", currencySEK, "KR":
+		return currencySEK, true
+	case currencyEUR, "€":
+		return currencyEUR, true
+	default:
+		return "", false
+	}
+}
+
+// applyPriceCurrency fills PriceSEK, PriceMin and PriceMax, converting
+// foreign prices with eurRate. PriceSEK is the lower bound, so filters and
+// buckets go by the cheapest option. Prices without a currency marker are
+// assumed to be in fallback. Per-dish prices in the menu become Items.
+// When the price lists a weekday and a weekend price, the variants go to
+// PriceTiers and Price becomes the one for day.
+func applyPriceCurrency(restaurants []Restaurant, day int, fallback string, eurRate float64) {
+	for i := range restaurants {
+		restaurants[i].Items = parseMenuItems(restaurants[i].Menu, fallback, eurRate)
+		restaurants[i].PriceTiers = parsePriceTiers(restaurants[i].Price, fallback, eurRate)
+		if tier, ok := pickPriceTier(restaurants[i].PriceTiers, day); ok {
+			restaurants[i].Price = tier.Price
+		}
+		low, high, currency, ok := parsePrice(restaurants[i].Price)
+		if !ok {
+			restaurants[i].PriceSEK, restaurants[i].PriceMin, restaurants[i].PriceMax = 0, 0, 0
+			continue
+		}
+		restaurants[i].PriceSEK = toSEK(low, currency, fallback, eurRate)
+		restaurants[i].PriceMin = restaurants[i].PriceSEK
+		restaurants[i].PriceMax = toSEK(high, currency, fallback, eurRate)
+	}
+}
+
+// toSEK converts amount to SEK; an empty currency means fallback.
+func toSEK(amount float64, currency, fallback string, eurRate float64) float64 {
+	if currency == "" {
+		currency = fallback
+	}
+	if currency == currencyEUR {
+		return amount * eurRate
+	}
+	return amount
+}
+
+// PriceTier is one variant of a restaurant's price, e.g. the weekend price
+// in "Lunch 125 kr / Helg 165 kr".
+type PriceTier struct {
+	Price string `json:"price"`
+	// PriceSEK is the tier's (lower) amount in SEK.
+	PriceSEK float64 `json:"price_sek,omitempty"`
+	Weekend  bool    `json:"weekend,omitempty"`
+}
+
+// weekendPriceMarkers mark the weekend or holiday variant of a price.
+var weekendPriceMarkers = []string{"helg", "lör", "sön", "weekend", "röd dag", "röda dagar"}
+
+// priceTierSeparator splits the variants of a price text.
+var priceTierSeparator = regexp.MustCompile(`\s*(?:[/|;]|,\s|\soch\s)\s*`)
+
+// parsePriceTiers splits a price text listing both a weekday and a weekend
+// price into its variants. It returns nil when the text has fewer than two
+// priced variants or does not tell weekday and weekend apart, so a single
+// price keeps applying to every day.
+func parsePriceTiers(raw, fallback string, eurRate float64) []PriceTier {
+	text := normalizeSpaces(raw)
+	var segments []string
+	// Parts without an amount, as in "Lör/sön 165 kr", label the next one.
+	pending := ""
+	for _, part := range priceTierSeparator.Split(text, -1) {
+		if pending != "" {
+			part = pending + "/" + part
+		}
+		if !strings.ContainsAny(part, "0123456789") {
+			pending = part
+			continue
+		}
+		segments, pending = append(segments, part), ""
+	}
+	if len(segments) == 1 {
+		segments = splitAtWeekendMarker(segments[0])
+	}
+	if len(segments) < 2 {
+		return nil
+	}
+
+	var tiers []PriceTier
+	weekday, weekend := false, false
+	for _, segment := range segments {
+		tier := PriceTier{Price: segment, Weekend: hasWeekendMarker(segment)}
+		if amount, _, currency, ok := parsePrice(segment); ok {
+			tier.PriceSEK = toSEK(amount, currency, fallback, eurRate)
+		}
+		weekend = weekend || tier.Weekend
+		weekday = weekday || !tier.Weekend
+		tiers = append(tiers, tier)
+	}
+	if !weekday || !weekend {
+		return nil
+	}
+	return tiers
+}
+
+// splitAtWeekendMarker splits "Vardag 125 kr helg 165 kr" before the
+// weekend marker, or "Helg 165 kr vardag 125 kr" after the weekend price,
+// when there is an amount on both sides.
+func splitAtWeekendMarker(text string) []string {
+	lower := strings.ToLower(text)
+	for _, marker := range weekendPriceMarkers {
+		at := strings.Index(lower, marker)
+		if at < 0 {
+			continue
+		}
+		if at == 0 {
+			at = priceEnd(lower)
+		}
+		before, after := strings.TrimSpace(text[:at]), strings.TrimSpace(text[at:])
+		if strings.ContainsAny(before, "0123456789") && strings.ContainsAny(after, "0123456789") {
+			return []string{before, after}
+		}
+	}
+	return []string{text}
+}
+
+// priceEnd returns the offset just past the first amount in text and its
+// currency marker, if any.
+func priceEnd(text string) int {
+	_, rest, ok := leadingAmount(text)
+	if !ok {
+		return len(text)
+	}
+	trimmed := strings.TrimLeft(rest, " ")
+	for _, marker := range []string{"kr", "sek", ":-", "€", "eur"} {
+		if strings.HasPrefix(trimmed, marker) {
+			trimmed = trimmed[len(marker):]
+			break
+		}
+	}
+	return len(text) - len(trimmed)
+}
+
+func hasWeekendMarker(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range weekendPriceMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// pickPriceTier returns the first tier for day: a weekend one on Saturday
+// and Sunday, a weekday one otherwise.
+func pickPriceTier(tiers []PriceTier, day int) (PriceTier, bool) {
+	weekend := day == 6 || day == 7
+	for _, tier := range tiers {
+		if tier.Weekend == weekend {
+			return tier, true
+		}
+	}
+	return PriceTier{}, false
+}
+
+// dishPricePattern matches a menu line ending in a price with a currency
+// marker, e.g. "Köttbullar ... 95 kr", "Lax 129:50 kr" or "Pasta – 12,50 €". The marker is
+// required so times and quantities are not taken for prices.
+var dishPricePattern = regexp.MustCompile(`(?i)^(.+?)[\s.…:–—-]+(\d+(?:[.,]\d{1,2}|:\d{2})?\s*(?:kr|sek|:-|€|eur))\.?$`)
+
+// parseMenuItems splits menu lines with a trailing price into dish and
+// price, converting the price to SEK like applyPriceCurrency.
+func parseMenuItems(menu []string, fallback string, eurRate float64) []MenuItem {
+	var items []MenuItem
+	for _, line := range menu {
+		match := dishPricePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		item := MenuItem{Dish: strings.TrimSpace(match[1]), Price: match[2], Line: line}
+		if amount, _, currency, ok := parsePrice(match[2]); ok {
+			item.PriceSEK = toSEK(amount, currency, fallback, eurRate)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// filterByMaxPrice keeps restaurants priced at or below maxPrice. With
+// dishLevel, restaurants with per-dish prices are judged by those instead:
+// they are kept when any dish fits, and dishes over budget are removed
+// from Items and Menu.
+func filterByMaxPrice(restaurants []Restaurant, maxPrice float64, dishLevel bool) []Restaurant {
+	var filtered []Restaurant
+	for _, r := range restaurants {
+		if dishLevel && len(r.Items) > 0 {
+			if r, ok := affordableDishes(r, maxPrice); ok {
+				filtered = append(filtered, r)
+			}
+			continue
+		}
+		if r.PriceSEK > 0 && r.PriceSEK <= maxPrice {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// affordableDishes drops the dishes priced above maxPrice and reports
+// whether any are left. Dishes with an unknown price are kept.
+func affordableDishes(r Restaurant, maxPrice float64) (Restaurant, bool) {
+	over := map[string]bool{}
+	var items []MenuItem
+	for _, item := range r.Items {
+		if item.PriceSEK > maxPrice {
+			over[item.Line] = true
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return r, false
+	}
+	var menu []string
+	for _, line := range r.Menu {
+		if !over[line] {
+			menu = append(menu, line)
+		}
+	}
+	r.Items, r.Menu = items, menu
+	return r, true
+}
+
+func parseMaxPrice(input string) (float64, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(input, ",", "."), 64)
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("invalid --max-price %q (use e.g. 120)", input)
+	}
+	return amount, nil
+}
+
+// defaultPriceBuckets gives the ranges under 100, 100-129, 130-159 and 160+.
+var defaultPriceBuckets = []float64{100, 130, 160}
+
+type priceBucket struct {
+	Label       string
+	Restaurants []Restaurant
+}
+
+// bucketRestaurants groups restaurants by PriceSEK using ascending lower
+// bounds. Empty buckets are dropped; unknown prices go last.
+func bucketRestaurants(restaurants []Restaurant, bounds []float64) []priceBucket {
+	buckets := make([]priceBucket, len(bounds)+2)
+	buckets[0].Label = fmt.Sprintf("under %s kr", formatAmount(bounds[0]))
+	for i := 1; i < len(bounds); i++ {
+		buckets[i].Label = fmt.Sprintf("%s–%s kr", formatAmount(bounds[i-1]), formatAmount(bounds[i]-1))
+	}
+	buckets[len(bounds)].Label = fmt.Sprintf("%s+ kr", formatAmount(bounds[len(bounds)-1]))
+	unknown := len(bounds) + 1
+	buckets[unknown].Label = "unknown price"
+
+	for _, r := range restaurants {
+		if r.PriceSEK <= 0 {
+			buckets[unknown].Restaurants = append(buckets[unknown].Restaurants, r)
+			continue
+		}
+		idx := sort.SearchFloat64s(bounds, r.PriceSEK)
+		if idx < len(bounds) && bounds[idx] == r.PriceSEK {
+			idx++
+		}
+		buckets[idx].Restaurants = append(buckets[idx].Restaurants, r)
+	}
+
+	var filled []priceBucket
+	for _, bucket := range buckets {
+		if len(bucket.Restaurants) > 0 {
+			filled = append(filled, bucket)
+		}
+	}
+	return filled
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+const (
+	priceFormatRaw    = "raw"
+	priceFormatKr     = "kr"
+	priceFormatSymbol = "symbol"
+)
+
+// formatPrice renders the parsed SEK price (or range) uniformly, falling
+// back to the site's own text when the price could not be parsed.
+func formatPrice(r Restaurant, format string) string {
+	if r.PriceSEK <= 0 {
+		return r.Price
+	}
+	amount := formatSEK(r.PriceSEK)
+	if r.PriceMax > r.PriceSEK {
+		amount += "–" + formatSEK(r.PriceMax)
+	}
+	switch format {
+	case priceFormatKr:
+		return amount + " kr"
+	case priceFormatSymbol:
+		return amount + ":-"
+	default:
+		return r.Price
+	}
+}
+
+// formatSEK drops the decimals for whole kronor and uses a Swedish comma
+// otherwise.
+func formatSEK(amount float64) string {
+	if amount == float64(int64(amount)) {
+		return strconv.FormatInt(int64(amount), 10)
+	}
+	return strings.ReplaceAll(strconv.FormatFloat(amount, 'f', 2, 64), ".", ",")
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		raw       string
+		low, high float64
+		currency  string
+		ok        bool
+	}{
+		{"129:-", 129, 129, currencySEK, true},
+		{"129:50", 129.5, 129.5, "", true},
+		{"129:50 kr", 129.5, 129.5, currencySEK, true},
+		{"129,-", 129, 129, currencySEK, true},
+		{"129 kr", 129, 129, currencySEK, true},
+		{"129\u00a0kr", 129, 129, currencySEK, true},
+		{"12,50\u00a0€", 12.5, 12.5, currencyEUR, true},
+		{"129 SEK", 129, 129, currencySEK, true},
+		{"Pris: 129 kr", 129, 129, currencySEK, true},
+		{"129.50", 129.5, 129.5, "", true},
+		{"12,50 €", 12.5, 12.5, currencyEUR, true},
+		{"€12.50", 12.5, 12.5, currencyEUR, true},
+		{"10 EUR", 10, 10, currencyEUR, true},
+		{"95–125 kr", 95, 125, currencySEK, true},
+		{"95 - 125 kr", 95, 125, currencySEK, true},
+		{"110—145:-", 110, 145, currencySEK, true},
+		{"125-95 kr", 125, 125, currencySEK, true},
+		{"95 -", 95, 95, "", true},
+		{"", 0, 0, "", false},
+		{"Fråga personalen", 0, 0, "", false},
+	}
+	for _, tt := range tests {
+		low, high, currency, ok := parsePrice(tt.raw)
+		if low != tt.low || high != tt.high || currency != tt.currency || ok != tt.ok {
+			t.Errorf("parsePrice(%q) = %v, %v, %q, %v; want %v, %v, %q, %v",
+				tt.raw, low, high, currency, ok, tt.low, tt.high, tt.currency, tt.ok)
+		}
+	}
+}
+
+func TestToSEK(t *testing.T) {
+	tests := []struct {
+		raw      string
+		fallback string
+		want     float64
+	}{
+		{"129:-", currencySEK, 129},
+		{"129:50", currencySEK, 129.5},
+		{"12,50 €", currencySEK, 125},
+		{"12,50", currencyEUR, 125},
+	}
+	for _, tt := range tests {
+		amount, _, currency, ok := parsePrice(tt.raw)
+		if !ok {
+			t.Fatalf("parsePrice(%q) failed", tt.raw)
+		}
+		if got := toSEK(amount, currency, tt.fallback, 10); got != tt.want {
+			t.Errorf("toSEK(%q, fallback %s) = %v, want %v", tt.raw, tt.fallback, got, tt.want)
+		}
+	}
+}
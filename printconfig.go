@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+// printResolvedConfig writes the merged Options as YAML, one key per field,
+// with the source of the layered values (flag, config or default) as a
+// trailing comment.
+func printResolvedConfig(w io.Writer, opts Options, sources map[string]string) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(opts)
+	for i := 0; i < v.NumField(); i++ {
+		name := snakeCase(v.Type().Field(i).Name)
+		resolved := resolvedValue(name, v.Field(i))
+		switch {
+		case name == "cache_until":
+			// Shown as part of cache_ttl.
+			continue
+		case name == "archive":
+			// The --from-json file is shown as from_json.
+			continue
+		case name == "cache_ttl" && opts.CacheTTL == untilCacheTTL:
+			resolved = fmt.Sprintf("until:%02d:%02d", int(opts.CacheUntil.Hours()), int(opts.CacheUntil.Minutes())%60)
+		}
+		value := &yaml.Node{}
+		if err := value.Encode(resolved); err != nil {
+			return fmt.Errorf("could not encode %s: %w", name, err)
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+		if source := sources[name]; source != "" {
+			key.LineComment = source
+		}
+		root.Content = append(root.Content, key, value)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resolvedValue makes fields readable: durations, days and areas as labels
+// and the rate limiter as its rate. Header values are masked, since they
+// often carry cookies or tokens and the output ends up in bug reports.
+func resolvedValue(name string, field reflect.Value) any {
+	switch value := field.Interface().(type) {
+	case time.Duration:
+		if value == autoCacheTTL {
+			return "auto"
+		}
+		return value.String()
+	case *rate.Limiter:
+		if value == nil {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%g/s", float64(value.Limit()))
+	case map[string]string:
+		if name == "headers" {
+			masked := make(map[string]string, len(value))
+			for key := range value {
+				masked[key] = redacted
+			}
+			return masked
+		}
+	case []AreaConfig:
+		labels := make([]string, len(value))
+		for i, area := range value {
+			labels[i] = areaLabel(area)
+		}
+		return labels
+	case int:
+		if name == "day" {
+			return dayLabel(value)
+		}
+	case []int:
+		if name == "merge_days" {
+			labels := make([]string, len(value))
+			for i, day := range value {
+				labels[i] = dayLabel(day)
+			}
+			return labels
+		}
+	}
+	return field.Interface()
+}
+
+// redacted replaces header values in --print-config.
+const redacted = "<redacted>"
+
+// configSources says where the layered options came from.
+func configSources(flags Flags, cfg *Config) map[string]string {
+	pick := func(flag, config string) string {
+		switch {
+		case strings.TrimSpace(flag) != "":
+			return "flag"
+		case strings.TrimSpace(config) != "":
+			return "config"
+		default:
+			return "default"
+		}
+	}
+	sources := map[string]string{
+		"cache_dir": pick(flags.CacheDir, cfg.CacheDir),
+		"cache_ttl": pick(flags.CacheTTL, cfg.CacheTTL),
+		"format":    pick(flags.Format, cfg.OutputFormat),
+		"day":       pick(flags.Day, ""),
+	}
+	switch {
+	case flags.FromJSON != "":
+		sources["areas"] = "from-json"
+	case flags.Stdin:
+		sources["areas"] = "stdin"
+	case flags.Postcode != "":
+		sources["areas"] = "flag (postcode)"
+	case len(flags.Areas) > 0 || flags.City != "":
+		sources["areas"] = "flag"
+	default:
+		sources["areas"] = "config"
+	}
+	if len(cfg.HTTPHeaders) > 0 {
+		sources["headers"] = "config and flags"
+	}
+	return sources
+}
+
+// snakeCase turns a field name like CacheTTL into cache_ttl.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintResolvedConfigMasksHeaders(t *testing.T) {
+	opts := Options{Headers: map[string]string{
+		"Cookie":        "session=hunter2",
+		"Authorization": "Bearer s3cret",
+	}}
+	var out strings.Builder
+	if err := printResolvedConfig(&out, opts, nil); err != nil {
+		t.Fatalf("printResolvedConfig: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "s3cret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("output contains header value %q:\n%s", secret, out.String())
+		}
+	}
+	for _, key := range []string{"Cookie: <redacted>", "Authorization: <redacted>"} {
+		if !strings.Contains(out.String(), key) {
+			t.Errorf("output lacks %q:\n%s", key, out.String())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// remoteConfigTTL is how long a config fetched from a URL is reused before
+// it is fetched again.
+const remoteConfigTTL = time.Hour
+
+// isConfigURL reports whether --config names a shared config to fetch
+// rather than a local file. Plain http:// counts so it is refused by
+// loadRemoteConfig instead of being read as a file name.
+func isConfigURL(path string) bool {
+	lower := strings.ToLower(strings.TrimSpace(path))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// remoteConfigCacheName names the cached copy of a config URL.
+func remoteConfigCacheName(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return "config_" + hex.EncodeToString(sum[:])[:12] + ".yaml"
+}
+
+// loadRemoteConfig returns the YAML of a config URL, from the cache while it
+// is younger than remoteConfigTTL. When the fetch fails, an older cached copy
+// is used with a warning. Only https:// is accepted, so the config cannot be
+// swapped on the way.
+func loadRemoteConfig(url string) ([]byte, error) {
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(url)), "https://") {
+		return nil, fmt.Errorf("config URL %s must use https://", url)
+	}
+	dir := defaultCacheDir()
+	name := remoteConfigCacheName(url)
+	if reader, _, ok := tryCache(dir, name, remoteConfigTTL); ok {
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+
+	data, err := fetchRemoteConfig(url)
+	if err == nil {
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				log.Printf("could not create cache directory: %v", err)
+			} else if err := writeFileAtomic(filepath.Join(dir, name), data, 0o644); err != nil {
+				log.Printf("could not cache config (%s): %v", url, err)
+			}
+		}
+		return data, nil
+	}
+	if dir != "" {
+		path := filepath.Join(dir, name)
+		if cached, readErr := os.ReadFile(path); readErr == nil {
+			updated := ""
+			if info, statErr := os.Stat(path); statErr == nil {
+				updated = " from " + info.ModTime().Format("2006-01-02 15:04")
+			}
+			log.Printf("could not fetch config (%s), using the cached copy%s: %v", url, updated, err)
+			return cached, nil
+		}
+	}
+	return nil, fmt.Errorf("could not fetch config (%s): %w", url, err)
+}
+
+// fetchRemoteConfig downloads a config and checks that it parses, so a
+// broken response never replaces a good cached copy.
+func fetchRemoteConfig(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	resp, err := fetchHTML(ctx, url, Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.Request.URL.Scheme != "https" {
+		return nil, fmt.Errorf("redirected to %s, which is not https://", resp.Request.URL)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("not a valid config: %w", err)
+	}
+	return data, nil
+}
+
+// dropUntrustedKeys clears the keys a config fetched from url may not set,
+// with a warning for each one that was set: translate_cmd runs a command on
+// this machine, and cache_dir decides where files are written (and what
+// `cache clear` deletes). Hooks are flag-only, so they cannot come from a
+// config at all.
+func dropUntrustedKeys(cfg *Config, url string) {
+	if strings.TrimSpace(cfg.TranslateCmd) != "" {
+		log.Printf("ignoring translate_cmd from config %s: commands are only run from a local config", url)
+		cfg.TranslateCmd = ""
+	}
+	if strings.TrimSpace(cfg.CacheDir) != "" {
+		log.Printf("ignoring cache_dir from config %s: use --cache-dir or a local config", url)
+		cfg.CacheDir = ""
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigRefusesPlainHTTP(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	if _, err := loadConfig("http://example.com/config.yaml"); err == nil || !strings.Contains(err.Error(), "https://") {
+		t.Errorf("loadConfig over http: err = %v, want an https error", err)
+	}
+}
+
+func TestLoadConfigDropsUntrustedKeys(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "city: goteborg\narea: garda_161\ntranslate_cmd: touch /tmp/owned\ncache_dir: /home\n")
+	}))
+	defer server.Close()
+	transport := httpClient.Transport
+	httpClient.Transport = server.Client().Transport
+	defer func() { httpClient.Transport = transport }()
+
+	cfg, err := loadConfig(server.URL + "/config.yaml")
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.Area != "garda_161" {
+		t.Errorf("area = %q, want garda_161", cfg.Area)
+	}
+	if cfg.TranslateCmd != "" {
+		t.Errorf("translate_cmd = %q, want it dropped from a remote config", cfg.TranslateCmd)
+	}
+	if cfg.CacheDir != "" {
+		t.Errorf("cache_dir = %q, want it dropped from a remote config", cfg.CacheDir)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// runRepeat fetches every area once and then re-filters the parsed
+// restaurants in memory for each query typed at the prompt. It returns the
+// process exit code.
+func runRepeat(opts Options) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	var loaded []areaResult
+	for _, area := range opts.Areas {
+		restaurants, info, err := loadRestaurants(ctx, opts, area)
+		if err != nil {
+			slog.Error(err.Error(), "area", areaLabel(area))
+			continue
+		}
+		loaded = append(loaded, areaResult{Area: area, Day: opts.Day, Info: info, Restaurants: restaurants})
+	}
+	cancel()
+	if len(loaded) == 0 {
+		return 1
+	}
+
+	// The memo lives as long as loaded, so a new fetch starts empty.
+	memo := filterMemo{}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Filter (text, name:..., menu:..., empty for all, q to quit): ")
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "q" || line == "quit" || (err != nil && line == "") {
+			return 0
+		}
+		fmt.Println()
+
+		query := parseRepeatQuery(opts, line)
+		nameQuery, menuQuery := effectiveQueries(query)
+		for _, result := range loaded {
+			result.Restaurants = memo.filter(result, query, nameQuery, menuQuery)
+			printAreaText(result, query, nameQuery, menuQuery, strings.TrimSpace(query.Search))
+		}
+	}
+}
+
+// parseRepeatQuery turns a prompt line into filter options. "name:" and
+// "menu:" prefixes target one field; anything else searches both.
+func parseRepeatQuery(opts Options, line string) Options {
+	opts.Name, opts.Menu, opts.Search = "", "", ""
+	switch {
+	case strings.HasPrefix(line, "name:"):
+		opts.Name = strings.TrimSpace(strings.TrimPrefix(line, "name:"))
+	case strings.HasPrefix(line, "menu:"):
+		opts.Menu = strings.TrimSpace(strings.TrimPrefix(line, "menu:"))
+	default:
+		opts.Search = line
+	}
+	return opts
+}
+
+// filterMemo remembers applyFilters results per area, day and filter set so
+// repeating a query does not redo the fuzzy matching.
+type filterMemo map[string][]Restaurant
+
+func (m filterMemo) filter(result areaResult, opts Options, nameQuery, menuQuery string) []Restaurant {
+	key := fmt.Sprintf("%s|%d|%q|%q|%q|%q|%q|%t|%t|%g|%t|%q|%t|%s|%t",
+		areaLabel(result.Area), result.Day, nameQuery, menuQuery, opts.Search, opts.Address, opts.Category,
+		opts.NameExact, opts.CaseSensitive, opts.MaxPrice, opts.DishPrice, opts.Missing, opts.HasLink, opts.Sort, opts.OpenNow)
+	if restaurants, ok := m[key]; ok {
+		return restaurants
+	}
+	restaurants := applyFilters(result.Restaurants, opts, nameQuery, menuQuery)
+	m[key] = restaurants
+	return restaurants
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// areaDirectoryCacheName is the cache file of a city's area directory, as
+// written by --discover.
+func areaDirectoryCacheName(city string) string {
+	return city + "_areas.html"
+}
+
+// resolveAreaSlugs replaces --area slugs that are not in the city's cached
+// area directory with the area they fuzzy-match, e.g. "gard" with
+// "garda_161". An ambiguous slug is asked about when stdin is a terminal
+// and is an error otherwise. Slugs are kept as typed when the directory is
+// not cached or nothing matches.
+func resolveAreaSlugs(opts Options) ([]AreaConfig, error) {
+	directories := map[string][]AreaLink{}
+	resolved := make([]AreaConfig, 0, len(opts.Areas))
+	for _, area := range opts.Areas {
+		if area.Area == "" {
+			resolved = append(resolved, area)
+			continue
+		}
+		links, ok := directories[area.City]
+		if !ok {
+			links = cachedAreaDirectory(opts.CacheDir, area.City)
+			directories[area.City] = links
+		}
+		candidates := matchAreaSlug(area.Area, links)
+		switch {
+		case len(candidates) == 0:
+			if len(links) > 0 {
+				slog.Debug("area slug not in the area directory, using it as typed", "city", area.City, "area", area.Area)
+			}
+		case len(candidates) == 1:
+			if candidates[0].Slug != area.Area {
+				slog.Debug("resolved area slug", "city", area.City, "typed", area.Area, "slug", candidates[0].Slug)
+				area.Area = candidates[0].Slug
+			}
+		default:
+			slug, err := pickAreaSlug(area, candidates)
+			if err != nil {
+				return nil, err
+			}
+			area.Area = slug
+		}
+		resolved = append(resolved, area)
+	}
+	return resolved, nil
+}
+
+// cachedAreaDirectory reads the city's cached area directory regardless of
+// its age, since areas rarely change. It returns nil when there is none.
+func cachedAreaDirectory(dir, city string) []AreaLink {
+	if dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, areaDirectoryCacheName(city)))
+	if err != nil {
+		return nil
+	}
+	links, err := parseAreaLinks(bytes.NewReader(data), city)
+	if err != nil {
+		return nil
+	}
+	return links
+}
+
+// matchAreaSlug finds the areas meant by slug: an exact slug alone, else
+// the areas whose slug or name contains it after normalization, else those
+// it fuzzy-matches. Fuzzy candidates are ranked best first and only the
+// closest ones kept.
+func matchAreaSlug(slug string, links []AreaLink) []AreaLink {
+	for _, link := range links {
+		if strings.EqualFold(link.Slug, slug) {
+			return []AreaLink{link}
+		}
+	}
+	query := normalizeToken(slug)
+	if query == "" {
+		return nil
+	}
+	var contains []AreaLink
+	for _, link := range links {
+		if strings.Contains(normalizeToken(link.Slug), query) || strings.Contains(normalizeToken(link.Name), query) {
+			contains = append(contains, link)
+		}
+	}
+	if len(contains) > 0 {
+		return contains
+	}
+
+	best := -1
+	var nearest []AreaLink
+	for _, link := range links {
+		dist, ok := safeRankMatchFold(query, normalizeToken(link.Name))
+		if !ok || dist < 0 || dist > fuzzThreshold(len(query)) {
+			continue
+		}
+		switch {
+		case best < 0 || dist < best:
+			best, nearest = dist, []AreaLink{link}
+		case dist == best:
+			nearest = append(nearest, link)
+		}
+	}
+	return nearest
+}
+
+// pickAreaSlug asks which of candidates was meant by area's slug.
+func pickAreaSlug(area AreaConfig, candidates []AreaLink) (string, error) {
+	var slugs []string
+	for _, c := range candidates {
+		slugs = append(slugs, c.Slug)
+	}
+	if !isInteractive() {
+		return "", fmt.Errorf("area %q is ambiguous in %s: %s; use the full slug", area.Area, area.City, strings.Join(slugs, ", "))
+	}
+	fmt.Printf("Area %q matches several areas in %s:\n", area.Area, area.City)
+	for i, c := range candidates {
+		fmt.Printf("  %d. %s (%s)\n", i+1, c.Name, c.Slug)
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Which one? (1-%d): ", len(candidates))
+		line, err := reader.ReadString('\n')
+		if n, convErr := strconv.Atoi(strings.TrimSpace(line)); convErr == nil && n >= 1 && n <= len(candidates) {
+			return candidates[n-1].Slug, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("area %q is ambiguous in %s: %s", area.Area, area.City, strings.Join(slugs, ", "))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const rotateStateFile = "rotate.json"
+
+// rotateAvoidDays is how many previous days' picks --rotate steers away from.
+const rotateAvoidDays = 4
+
+// rotateState maps dates (2006-01-02) to the restaurant ID picked that day.
+type rotateState map[string]string
+
+// rotatePick narrows results to one restaurant for --rotate. The pick is
+// seeded by the menu's date, so reruns on the same day agree, and skips the
+// restaurants picked on up to rotateAvoidDays previous days. Picks are
+// remembered in the cache directory.
+func rotatePick(results []areaResult, opts Options, now time.Time) []areaResult {
+	monday := now.AddDate(0, 0, 1-weekdayToDay(now.Weekday()))
+	date := monday.AddDate(0, 0, opts.Day-1)
+	key := date.Format("2006-01-02")
+
+	state := loadRotateState(opts.CacheDir)
+
+	type candidate struct {
+		result int
+		index  int
+	}
+	var all []candidate
+	for i, result := range results {
+		for j, r := range result.Restaurants {
+			if r.ID == state[key] {
+				// Today's pick still matches; keep it.
+				return pickResult(results, i, j)
+			}
+			all = append(all, candidate{i, j})
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	// Avoid as many recent days' picks as the candidates allow, so there
+	// is variety even among few matches.
+	fresh := all
+	for days := rotateAvoidDays; days > 0; days-- {
+		recent := map[string]bool{}
+		for i := 1; i <= days; i++ {
+			if id := state[date.AddDate(0, 0, -i).Format("2006-01-02")]; id != "" {
+				recent[id] = true
+			}
+		}
+		var eligible []candidate
+		for _, c := range all {
+			if !recent[results[c.result].Restaurants[c.index].ID] {
+				eligible = append(eligible, c)
+			}
+		}
+		if len(eligible) > 0 {
+			fresh = eligible
+			break
+		}
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	picked := fresh[binary.BigEndian.Uint64(sum[:8])%uint64(len(fresh))]
+	state[key] = results[picked.result].Restaurants[picked.index].ID
+	for day := range state {
+		if day < date.AddDate(0, 0, -7).Format("2006-01-02") {
+			delete(state, day)
+		}
+	}
+	saveRotateState(opts.CacheDir, state)
+	return pickResult(results, picked.result, picked.index)
+}
+
+func pickResult(results []areaResult, i, j int) []areaResult {
+	result := results[i]
+	result.Restaurants = []Restaurant{result.Restaurants[j]}
+	return []areaResult{result}
+}
+
+func loadRotateState(dir string) rotateState {
+	state := rotateState{}
+	if dir == "" {
+		return state
+	}
+	path := filepath.Join(dir, rotateStateFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not read rotation state (%s): %v", path, err)
+		}
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("ignoring unreadable rotation state (%s): %v", path, err)
+		return rotateState{}
+	}
+	return state
+}
+
+func saveRotateState(dir string, state rotateState) {
+	if dir == "" {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	path := filepath.Join(dir, rotateStateFile)
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		log.Printf("could not write rotation state (%s): %v", path, err)
+	}
+}
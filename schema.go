@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writeSchema prints a JSON Schema for the --format json output. It is
+// derived from jsonArea and Restaurant by reflection so it follows the
+// structs the JSON formatter actually encodes.
+func writeSchema(w io.Writer) error {
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "kvartersmenyn-cli JSON output",
+		"description": "One object per area and day.",
+		"type":        "array",
+		"items":       typeSchema(reflect.TypeOf(jsonArea{})),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema describes t the way encoding/json would encode it. Fields tagged
+// omitempty or held by pointer are optional; all others are required.
+func typeSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property := typeSchema(field.Type)
+			if field.Type.Kind() == reflect.Slice && !strings.Contains(opts, "omitempty") {
+				// encoding/json writes a nil slice as null.
+				property["type"] = []string{"array", "null"}
+			}
+			properties[name] = property
+			if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+// validateSchema checks a decoded JSON value against a schema from
+// typeSchema. It understands only the keywords typeSchema emits and
+// reports the first mismatch with its JSON path, e.g. $[0].restaurants[2].
+func validateSchema(value any, schema map[string]any, path string) error {
+	if want, ok := schema["type"]; ok && !matchesSchemaType(value, want) {
+		return fmt.Errorf("%s: expected %v, got %s", path, want, jsonTypeName(value))
+	}
+	switch value := value.(type) {
+	case string:
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", path, value)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: missing %q", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				switch additional := schema["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unknown field %q", path, name)
+					}
+					continue
+				case map[string]any:
+					property = additional
+				default:
+					continue
+				}
+			}
+			if err := validateSchema(value[name], property, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesSchemaType reports whether value has the schema type want, which
+// is a type name or a list of them.
+func matchesSchemaType(value any, want any) bool {
+	switch want := want.(type) {
+	case []string:
+		for _, name := range want {
+			if matchesSchemaType(value, name) {
+				return true
+			}
+		}
+		return false
+	case string:
+		got := jsonTypeName(value)
+		if want == "integer" {
+			number, ok := value.(float64)
+			return ok && number == math.Trunc(number)
+		}
+		return got == want || (want == "number" && got == "integer")
+	}
+	return true
+}
+
+// jsonTypeName names the JSON type of a value decoded into any.
+func jsonTypeName(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
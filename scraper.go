@@ -1,20 +1,78 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"io"
+	"log/slog"
+	"net/url"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
 	"golang.org/x/net/html"
 )
 
 type Restaurant struct {
-	Name    string
-	Price   string
-	Address string
-	Phone   string
-	Link    string
-	Menu    []string
+	// ID is stable across days and areas, see restaurantID.
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Price   string   `json:"price"`
+	Address string   `json:"address,omitempty"`
+	Phone   string   `json:"phone,omitempty"`
+	Link    string   `json:"link,omitempty"`
+	Menu    []string `json:"menu"`
+	// Items are the menu lines that end in their own price, split into dish
+	// and price. Lines without one are only in Menu.
+	Items []MenuItem `json:"items,omitempty"`
+	// PriceSEK is the parsed price converted to SEK, or 0 when unknown. For
+	// a price range it is the lower bound.
+	PriceSEK float64 `json:"price_sek,omitempty"`
+	// PriceMin and PriceMax are the bounds of a price range in SEK; both
+	// equal PriceSEK for a single price.
+	PriceMin float64 `json:"price_min,omitempty"`
+	PriceMax float64 `json:"price_max,omitempty"`
+	// PriceTiers are the weekday and weekend variants when the listing
+	// gives both; Price and PriceSEK are then the one for the day shown.
+	PriceTiers []PriceTier `json:"price_tiers,omitempty"`
+	// Updated is set by --highlight-updated when the menu changed since the
+	// previous live fetch.
+	Updated bool `json:"updated,omitempty"`
+	// Days lists the weekdays a restaurant was seen on with --merge-days.
+	Days []string `json:"days,omitempty"`
+	// Rank is the 1-based position in the site's listing.
+	Rank int `json:"rank"`
+	// Category is the cuisine or category inferred from the name and menu
+	// with --infer-category (or --category). The listing has no category
+	// markup to read it from.
+	Category string `json:"category,omitempty"`
+	// CategoryInferred is set when Category was guessed from the menu.
+	CategoryInferred bool `json:"category_inferred,omitempty"`
+	// UnstructuredMenu is set when the menu could not be split into lines
+	// and Menu holds the block's raw text as a single line instead.
+	UnstructuredMenu bool `json:"unstructured_menu,omitempty"`
+	// Hours are the serving hours found in the menu, e.g. "11:00–14:00".
+	Hours string `json:"hours,omitempty"`
+	// Matches records how each text filter (name, menu, address) matched,
+	// for --explain.
+	Matches []fieldMatch `json:"-"`
+	// Extra holds fields added by a --post-process command, e.g. a rating.
+	Extra map[string]any `json:"extra,omitempty"`
+	// RawMenu and RawAddress keep the scraped text before normalization for
+	// --format debug.
+	RawMenu    []string `json:"-"`
+	RawAddress string   `json:"-"`
+}
+
+// MenuItem is a menu line with a trailing price, e.g. "Köttbullar ... 95 kr".
+type MenuItem struct {
+	Dish  string `json:"dish"`
+	Price string `json:"price"`
+	// PriceSEK is Price converted to SEK, or 0 when unknown.
+	PriceSEK float64 `json:"price_sek,omitempty"`
+	// Line is the menu line the item was parsed from.
+	Line string `json:"-"`
 }
 
 // parseRestaurants scrapes the HTML into a list of restaurants.
@@ -33,24 +91,88 @@ func parseRestaurants(r io.Reader) ([]Restaurant, error) {
 		}
 
 		price := normalizeSpaces(s.Find(".price-rl .price").First().Text())
-		menuLines := extractMenuLines(s.Find("div.rest-menu p.t_lunch").First())
-		addrText := normalizeSpaces(s.Find(".divider p").First().Text())
+		menuSel := s.Find("div.rest-menu p.t_lunch").First()
+		menuLines := extractMenuLines(menuSel)
+		unstructured := false
+		if len(menuLines) == 0 {
+			if text := unstructuredMenuText(s.Find("div.rest-menu").First()); text != "" {
+				slog.Debug("menu not structured, using the block's raw text", "restaurant", name)
+				menuLines, unstructured = []string{text}, true
+			}
+		}
+		rawAddress := s.Find(".divider p").First().Text()
+		addrText := normalizeSpaces(rawAddress)
 		address, phone := splitAddressAndPhone(addrText)
 		link, _ := s.Find("div.name h5.t_lunch a").First().Attr("href")
 
 		restaurants = append(restaurants, Restaurant{
-			Name:    name,
-			Price:   price,
-			Address: address,
-			Phone:   phone,
-			Link:    link,
-			Menu:    menuLines,
+			ID:               restaurantID(name, address, link),
+			Name:             name,
+			Price:            price,
+			Address:          address,
+			Phone:            phone,
+			Link:             link,
+			Menu:             menuLines,
+			UnstructuredMenu: unstructured,
+			Hours:            parseLunchHours(menuLines),
+			Rank:             len(restaurants) + 1,
+			RawMenu:          rawMenuLines(menuSel),
+			RawAddress:       rawAddress,
 		})
 	})
 
 	return restaurants, nil
 }
 
+// minUnstructuredMenu is how many characters a menu block without a usable
+// menu paragraph must hold before its text is shown as the menu; less is
+// more likely a stray fragment than a menu.
+const minUnstructuredMenu = 10
+
+// unstructuredMenuText is the raw text of a row's menu block, for blocks
+// whose menu paragraph is missing or malformed. It is empty when the row
+// has no menu block or too little text in it, so genuinely empty menus
+// stay empty.
+func unstructuredMenuText(block *goquery.Selection) string {
+	if block.Length() == 0 {
+		return ""
+	}
+	// Join the text nodes with spaces so "<div>a</div><div>b</div>" does
+	// not run together as "ab".
+	var parts []string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			parts = append(parts, node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(block.Nodes[0])
+	text := normalizeSpaces(strings.Join(parts, " "))
+	if utf8.RuneCountInString(text) < minUnstructuredMenu {
+		return ""
+	}
+	return text
+}
+
+// restaurantID hashes the path of the restaurant's link when there is one,
+// otherwise its normalized name and address, so formatting differences in
+// the listing do not change the ID.
+func restaurantID(name, address, link string) string {
+	key := "name:" + normalizeToken(name) + "|" + normalizeToken(address)
+	if link = strings.TrimSpace(link); link != "" {
+		linkPath := link
+		if u, err := url.Parse(link); err == nil && u.Path != "" && u.Path != "/" {
+			linkPath = u.Host + strings.TrimSuffix(u.Path, "/")
+		}
+		key = "link:" + strings.ToLower(linkPath)
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
 func extractMenuLines(sel *goquery.Selection) []string {
 	if sel.Length() == 0 {
 		return nil
@@ -74,6 +196,45 @@ func extractMenuLines(sel *goquery.Selection) []string {
 	return cleaned
 }
 
+// rawMenuLines splits the menu at <br> without trimming or dropping lines.
+func rawMenuLines(sel *goquery.Selection) []string {
+	if sel.Length() == 0 {
+		return nil
+	}
+	var builder strings.Builder
+	for _, node := range sel.Nodes {
+		writeNode(&builder, node)
+	}
+	return strings.Split(builder.String(), "\n")
+}
+
+// cleanMenuLines repairs menus split by stray <br> tags: a line starting in
+// lowercase after one without closing punctuation is joined to it, and
+// remaining fragments shorter than minLen runes are dropped.
+func cleanMenuLines(lines []string, minLen int) []string {
+	var cleaned []string
+	for _, line := range lines {
+		if n := len(cleaned); n > 0 && isContinuation(cleaned[n-1], line) {
+			cleaned[n-1] += " " + line
+			continue
+		}
+		if utf8.RuneCountInString(line) < minLen {
+			continue
+		}
+		cleaned = append(cleaned, line)
+	}
+	return cleaned
+}
+
+func isContinuation(prev, line string) bool {
+	first, _ := utf8.DecodeRuneInString(line)
+	if !unicode.IsLower(first) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	return !strings.ContainsRune(".!?:", last)
+}
+
 // textWithBreaks keeps <br> as line breaks when extracting text.
 func textWithBreaks(sel *goquery.Selection) string {
 	var builder strings.Builder
@@ -114,3 +275,42 @@ func normalizeSpaces(s string) string {
 	s = strings.ReplaceAll(s, "\u00a0", " ")
 	return strings.Join(strings.Fields(s), " ")
 }
+
+// AreaLink is one entry in a city's area directory.
+type AreaLink struct {
+	Name string
+	Slug string
+}
+
+// parseAreaLinks collects the distinct area links on a city page.
+func parseAreaLinks(r io.Reader, city string) ([]AreaLink, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return areaLinksIn(doc.Selection, city), nil
+}
+
+// areaLinksIn collects the distinct links to areas in city within sel.
+func areaLinksIn(sel *goquery.Selection, city string) []AreaLink {
+	seen := map[string]bool{}
+	var areas []AreaLink
+	sel.Find("a[href*='/area/']").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		linkCity, slug, ok := parseAreaURL(href)
+		if !ok || slug == "" || seen[slug] || !validSlug(slug) {
+			return
+		}
+		// Links can point to neighbouring cities; keep the requested one.
+		if linkCity != city && !strings.Contains(href, "/city/"+city+"/") {
+			return
+		}
+		name := normalizeSpaces(s.Text())
+		if name == "" {
+			name = slug
+		}
+		seen[slug] = true
+		areas = append(areas, AreaLink{Name: name, Slug: slug})
+	})
+	return areas
+}
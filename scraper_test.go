@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// listingRow wraps a menu paragraph in the markup of one listing row.
+func listingRow(menu string) string {
+	return listingRowWith(`<div class="rest-menu"><p class="t_lunch">` + menu + `</p></div>`)
+}
+
+// listingRowWith builds one listing row around the given menu block markup.
+func listingRowWith(menuBlock string) string {
+	return `<html><body><div class="row t_lunch">` +
+		`<div class="name"><h5 class="t_lunch"><a href="/rest/1">Koka Bistro</a></h5></div>` +
+		`<div class="price-rl"><span class="price">129:-</span></div>` +
+		menuBlock +
+		`<div class="divider"><p>ADRESS: Kungsgatan 12</p></div>` +
+		`</div></body></html>`
+}
+
+func TestCleanMenuLinesBrokenMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		menu string
+		want []string
+	}{
+		{
+			name: "well formed",
+			menu: "Köttbullar med potatis<br>Lax med dillsås",
+			want: []string{"Köttbullar med potatis", "Lax med dillsås"},
+		},
+		{
+			name: "conjunction on its own line",
+			menu: "Lax med dillsås<br>och<br>Vegetarisk lasagne",
+			want: []string{"Lax med dillsås och", "Vegetarisk lasagne"},
+		},
+		{
+			name: "dish split mid sentence",
+			menu: "Stekt fläsk med<br>löksås och kokt potatis<br>Pannkakor med sylt",
+			want: []string{"Stekt fläsk med löksås och kokt potatis", "Pannkakor med sylt"},
+		},
+		{
+			name: "lowercase after closing punctuation",
+			menu: "Dagens soppa.<br>serveras med bröd",
+			want: []string{"Dagens soppa.", "serveras med bröd"},
+		},
+		{
+			name: "stray fragments and doubled breaks",
+			menu: "Kycklinggryta<br><br>-<br>   <br>Fiskgratäng<br>*",
+			want: []string{"Kycklinggryta", "Fiskgratäng"},
+		},
+		{
+			name: "break inside inline markup",
+			menu: "<b>Veckans vegetariska:<br></b>Halloumi med<br><i>couscous</i>",
+			want: []string{"Veckans vegetariska:", "Halloumi med couscous"},
+		},
+		{
+			name: "unclosed tags",
+			menu: "<span>Schnitzel med<br>kapris<br><span>Laxpudding",
+			want: []string{"Schnitzel med kapris", "Laxpudding"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restaurants, err := parseRestaurants(strings.NewReader(listingRow(tt.menu)))
+			if err != nil {
+				t.Fatalf("parseRestaurants: %v", err)
+			}
+			if len(restaurants) != 1 {
+				t.Fatalf("got %d restaurants, want 1", len(restaurants))
+			}
+			got := cleanMenuLines(restaurants[0].Menu, defaultMenuMinLine)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("menu = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanMenuLinesMinLength(t *testing.T) {
+	lines := []string{"Soppa", "Gröt", "Te", "Ris"}
+	got := cleanMenuLines(lines, 4)
+	want := []string{"Soppa", "Gröt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cleanMenuLines(%q, 4) = %q, want %q", lines, got, want)
+	}
+}
+
+func TestParseRestaurantsUnstructuredMenu(t *testing.T) {
+	tests := []struct {
+		name         string
+		menuBlock    string
+		want         []string
+		unstructured bool
+	}{
+		{
+			name:      "well formed",
+			menuBlock: `<div class="rest-menu"><p class="t_lunch">Pytt i panna<br>Kyckling med ris</p></div>`,
+			want:      []string{"Pytt i panna", "Kyckling med ris"},
+		},
+		{
+			// The parser closes the paragraph at the <div>, leaving it empty
+			// and the dishes beside it in the menu block.
+			name:         "malformed paragraph",
+			menuBlock:    `<div class="rest-menu"><p class="t_lunch"><div>Pytt i panna</div><div>Kyckling med ris</div></p></div>`,
+			want:         []string{"Pytt i panna Kyckling med ris"},
+			unstructured: true,
+		},
+		{
+			name:         "text outside the paragraph",
+			menuBlock:    `<div class="rest-menu">Dagens: <b>Pytt i panna</b> med ägg</div>`,
+			want:         []string{"Dagens: Pytt i panna med ägg"},
+			unstructured: true,
+		},
+		{
+			name:      "empty paragraph",
+			menuBlock: `<div class="rest-menu"><p class="t_lunch"></p></div>`,
+		},
+		{
+			name:      "stray fragment",
+			menuBlock: `<div class="rest-menu"><p class="t_lunch"></p>-</div>`,
+		},
+		{
+			name: "no menu block",
+		},
+		{
+			name:      "stray text elsewhere in the row",
+			menuBlock: `<span class="badge">Nyhet i området!</span>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restaurants, err := parseRestaurants(strings.NewReader(listingRowWith(tt.menuBlock)))
+			if err != nil {
+				t.Fatalf("parseRestaurants: %v", err)
+			}
+			if len(restaurants) != 1 {
+				t.Fatalf("got %d restaurants, want 1", len(restaurants))
+			}
+			r := restaurants[0]
+			if !reflect.DeepEqual(r.Menu, tt.want) || r.UnstructuredMenu != tt.unstructured {
+				t.Errorf("menu = %q (unstructured %v), want %q (unstructured %v)", r.Menu, r.UnstructuredMenu, tt.want, tt.unstructured)
+			}
+		})
+	}
+}
+
+func TestParseRestaurantsLeavesCategoryEmpty(t *testing.T) {
+	page := listingRowWith(`<div class="rest-menu"><p class="t_lunch">Sushi 10 bitar</p></div>` +
+		`<a class="categories-link" href="/kategorier">Alla kategorier</a>`)
+	restaurants, err := parseRestaurants(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parseRestaurants: %v", err)
+	}
+	if len(restaurants) != 1 || restaurants[0].Category != "" {
+		t.Errorf("restaurants = %+v, want one without a category", restaurants)
+	}
+}
+
+func TestParseRestaurantsRankIsListingOrder(t *testing.T) {
+	page := `<html><body>` +
+		`<div class="row t_lunch highlight"><div class="name"><h5 class="t_lunch"><a href="/rest/1">Koka Bistro</a></h5></div></div>` +
+		`<div class="row t_lunch"><div class="name"><h5 class="t_lunch"><a href="/rest/2">Sushi Yama</a></h5></div></div>` +
+		`<div class="row t_lunch"><div class="name"><h5 class="t_lunch"></h5></div></div>` +
+		`<div class="row t_lunch"><div class="name"><h5 class="t_lunch"><a href="/rest/3">Gårda Kök</a></h5></div></div>` +
+		`</body></html>`
+	restaurants, err := parseRestaurants(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parseRestaurants: %v", err)
+	}
+	var got []string
+	for _, r := range restaurants {
+		got = append(got, fmt.Sprintf("%d %s", r.Rank, r.Name))
+	}
+	want := []string{"1 Koka Bistro", "2 Sushi Yama", "3 Gårda Kök"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ranks = %q, want %q", got, want)
+	}
+}
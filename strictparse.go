@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// emptyListingPhrases are what a page says when it lists no lunches on
+// purpose, e.g. during holidays.
+var emptyListingPhrases = []string{"inga luncher", "ingen lunch", "inga lunchmenyer", "no lunch"}
+
+// maxIncompleteShare is the share of listing blocks without a name or price
+// above which --strict-parse treats a page as badly scraped.
+const maxIncompleteShare = 0.3
+
+// checkParse reports why a live page's parse looks degraded, for
+// --strict-parse. A page without restaurants passes only when it is an
+// umbrella page with sub-areas, a weekend or says it has no lunches.
+func checkParse(page []byte, restaurants []Restaurant, children []AreaConfig, day int) error {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return err
+	}
+	blocks := doc.Find("div.row.t_lunch").Length()
+
+	if len(restaurants) == 0 {
+		switch {
+		case blocks > 0:
+			return fmt.Errorf("%d listing block(s) but none with a restaurant name", blocks)
+		case len(children) > 0 || day >= 6:
+			return nil
+		}
+		text := strings.ToLower(doc.Text())
+		for _, phrase := range emptyListingPhrases {
+			if strings.Contains(text, phrase) {
+				return nil
+			}
+		}
+		return errors.New("no restaurants found and the page does not say there are no lunches")
+	}
+
+	// Blocks without a name are dropped by the parser, so they count too.
+	incomplete := blocks - len(restaurants)
+	for _, r := range restaurants {
+		if r.Price == "" {
+			incomplete++
+		}
+	}
+	if float64(incomplete) > maxIncompleteShare*float64(blocks) {
+		return fmt.Errorf("%d of %d listing block(s) lack a name or price", incomplete, blocks)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// subareaListSelector finds the list of sub-areas on an umbrella area's
+// page. Other area links, such as the city's area menu, are not sub-areas.
+const subareaListSelector = "[class*='subarea'], [class*='sub-area'], [id*='subarea'], [id*='sub-area']"
+
+// subareaLinks returns the areas listed as sub-areas on an area page.
+func subareaLinks(page []byte, area AreaConfig) []AreaConfig {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	var children []AreaConfig
+	for _, link := range areaLinksIn(doc.Find(subareaListSelector), area.City) {
+		if link.Slug != area.Area {
+			children = append(children, AreaConfig{City: area.City, Area: link.Slug, Headers: area.Headers})
+		}
+	}
+	return children
+}
+
+// loadSubareas fetches each child of an umbrella area and combines the
+// restaurants, skipping duplicates listed in several children. Children are
+// never expanded further.
+func loadSubareas(ctx context.Context, opts Options, parent AreaConfig, children []AreaConfig) ([]Restaurant, SourceInfo, error) {
+	opts.ExpandSubareas = false
+
+	var combined []Restaurant
+	seen := map[string]bool{}
+	info := SourceInfo{
+		Label:  fmt.Sprintf("%s + %d sub-area(s)", areaLabel(parent), len(children)),
+		Source: "cache",
+	}
+	for _, child := range children {
+		restaurants, childInfo, err := loadRestaurants(ctx, opts, child)
+		if err != nil {
+			return nil, SourceInfo{}, err
+		}
+		if childInfo.Source == "live" {
+			info.Source = "live"
+		}
+		for _, r := range restaurants {
+			key := strings.ToLower(r.Name) + "\x00" + strings.ToLower(r.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			combined = append(combined, r)
+		}
+	}
+	return combined, info, nil
+}
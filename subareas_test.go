@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubareaLinks(t *testing.T) {
+	area := AreaConfig{City: "goteborg", Area: "centrum"}
+	// The city's area menu is on every page, empty or not.
+	const menu = `<nav class="areas">
+<a href="/lunch/city/goteborg/area/centrum">Centrum</a>
+<a href="/lunch/city/goteborg/area/garda_161">Gårda</a>
+<a href="/lunch/city/goteborg/area/johanneberg_43">Johanneberg</a>
+</nav>`
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "empty day with only the area menu",
+			page: `<html><body>` + menu + `<p>Inga luncher idag</p></body></html>`,
+		},
+		{
+			name: "umbrella page",
+			page: `<html><body>` + menu + `<ul class="subareas">
+<li><a href="/lunch/city/goteborg/area/centrum">Centrum</a></li>
+<li><a href="/lunch/city/goteborg/area/inom_vallgraven_12">Inom Vallgraven</a></li>
+<li><a href="/lunch/city/goteborg/area/nordstaden_13">Nordstaden</a></li>
+<li><a href="/lunch/city/stockholm/area/city_1">Stockholm City</a></li>
+</ul></body></html>`,
+			want: []string{"inom_vallgraven_12", "nordstaden_13"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, child := range subareaLinks([]byte(tt.page), area) {
+				got = append(got, child.Area)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subareaLinks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// runStats are the run statistics --summary-json adds to the summary.
+type runStats struct {
+	Areas       int          `json:"areas"`
+	CacheHits   int          `json:"cache_hits"`
+	CacheMisses int          `json:"cache_misses"`
+	Prices      *priceStats  `json:"prices,omitempty"`
+	Timings     []areaTiming `json:"timings"`
+	DurationMS  int64        `json:"duration_ms"`
+}
+
+// priceStats summarize the known prices (SEK) of the matched restaurants.
+type priceStats struct {
+	Count   int     `json:"count"`
+	Total   float64 `json:"total"`
+	Average float64 `json:"average"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+}
+
+// areaTiming is how long one area and day took to load.
+type areaTiming struct {
+	Area        string `json:"area"`
+	Source      string `json:"source,omitempty"`
+	Restaurants int    `json:"restaurants"`
+	MS          int64  `json:"ms"`
+	Failed      bool   `json:"failed,omitempty"`
+	// city, slug and day label the area in --metrics-file.
+	city, slug, day string
+}
+
+// addArea records one loaded area and day. The cache counts follow the
+// source: a page read from cache is a hit, anything fetched live a miss.
+func (s *runStats) addArea(result areaResult, took time.Duration, failed bool) {
+	s.Areas++
+	switch {
+	case failed:
+		// Neither a hit nor a miss.
+	case result.Info.Source == "cache":
+		s.CacheHits++
+	default:
+		s.CacheMisses++
+	}
+	day := dayLabel(result.Day)
+	if len(result.Days) > 0 {
+		var days []string
+		for _, d := range result.Days {
+			days = append(days, dayLabel(d))
+		}
+		day = strings.Join(days, ",")
+	}
+	s.Timings = append(s.Timings, areaTiming{
+		Area:        resultLabel(result),
+		Source:      result.Info.Source,
+		Restaurants: len(result.Restaurants),
+		MS:          took.Milliseconds(),
+		Failed:      failed,
+		city:        result.Area.City,
+		slug:        result.Area.Area,
+		day:         day,
+	})
+}
+
+// addPrices folds the restaurants' parsed prices into the price stats.
+// Restaurants without a known price are left out.
+func (s *runStats) addPrices(restaurants []Restaurant) {
+	for _, r := range restaurants {
+		if r.PriceSEK <= 0 {
+			continue
+		}
+		if s.Prices == nil {
+			s.Prices = &priceStats{Min: math.Inf(1)}
+		}
+		p := s.Prices
+		p.Count++
+		p.Total += r.PriceSEK
+		p.Min = min(p.Min, r.PriceSEK)
+		p.Max = max(p.Max, r.PriceSEK)
+		p.Average = math.Round(p.Total/float64(p.Count)*100) / 100
+	}
+}
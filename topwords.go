@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultStopWords are left out of --top-words; stop_words in config adds
+// more. They are compared after normalizeToken.
+var defaultStopWords = []string{
+	"med", "och", "i", "pa", "av", "samt", "eller", "till", "fran", "for", "en", "ett", "som", "serveras",
+	"dagens", "dag", "lunch", "alt", "inkl", "ingar", "kr", "sek", "st", "var", "vi", "de", "den", "det",
+	"with", "and", "or", "of", "in", "the", "served",
+}
+
+// wordCount is one entry of --top-words.
+type wordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// topMenuWords tallies the words in the menus of results, skipping stop
+// words, numbers and single letters, and returns the n most frequent. A
+// restaurant listed in several areas is counted once per day. Words are
+// shown in the spelling first seen.
+func topMenuWords(results []areaResult, stopWords []string, n int) []wordCount {
+	stop := map[string]bool{}
+	for _, word := range append(defaultStopWords, stopWords...) {
+		stop[normalizeToken(word)] = true
+	}
+
+	counts := map[string]int{}
+	spelling := map[string]string{}
+	seen := map[string]bool{}
+	for _, result := range results {
+		for _, r := range result.Restaurants {
+			key := fmt.Sprintf("%s|%d", r.ID, result.Day)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			for _, line := range r.Menu {
+				for _, field := range strings.Fields(line) {
+					word := normalizeToken(field)
+					if len([]rune(word)) < 2 || stop[word] || allDigits(word) {
+						continue
+					}
+					counts[word]++
+					if _, ok := spelling[word]; !ok {
+						spelling[word] = strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+							return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+						}))
+					}
+				}
+			}
+		}
+	}
+
+	words := make([]wordCount, 0, len(counts))
+	for word, count := range counts {
+		words = append(words, wordCount{Word: spelling[word], Count: count})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Count != words[j].Count {
+			return words[i].Count > words[j].Count
+		}
+		return words[i].Word < words[j].Word
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
+
+// printTopWords prints the ranked list for --top-words in text output.
+func printTopWords(words []wordCount, results []areaResult) {
+	restaurants := 0
+	for _, result := range results {
+		restaurants += len(result.Restaurants)
+	}
+	printStyledLine(fmt.Sprintf("Top menu words — %d restaurant(s) in %d area(s)", restaurants, len(results)), styleBold)
+	fmt.Fprintln(output)
+	if len(words) == 0 {
+		fmt.Fprintln(output, "No menu words found.")
+		return
+	}
+	width := 0
+	for _, w := range words {
+		width = max(width, len([]rune(w.Word)))
+	}
+	for i, w := range words {
+		fmt.Fprintf(output, "%3d. %s:%s %d\n", i+1, w.Word, strings.Repeat(" ", width-len([]rune(w.Word))), w.Count)
+	}
+}
+
+func writeTopWordsJSON(w io.Writer, words []wordCount) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(words)
+}
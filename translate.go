@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// translateTimeout bounds one call of translate_cmd.
+const translateTimeout = 10 * time.Second
+
+// translator pipes menu lines through translate_cmd, remembering each
+// line's translation for the rest of the run. After the first failure it
+// stops calling the command so a missing tool costs one warning.
+type translator struct {
+	command []string
+	cache   map[string]string
+	failed  bool
+}
+
+func newTranslator(command string) *translator {
+	return &translator{command: strings.Fields(command), cache: map[string]string{}}
+}
+
+// translate returns the translation of line, or "" when it is unavailable
+// or the same as the original.
+func (t *translator) translate(line string) string {
+	if t == nil || t.failed || len(t.command) == 0 {
+		return ""
+	}
+	if translated, ok := t.cache[line]; ok {
+		return translated
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), translateTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.command[0], t.command[1:]...)
+	cmd.Stdin = strings.NewReader(line + "\n")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		log.Printf("translation disabled: could not run translate_cmd %q: %v", strings.Join(t.command, " "), err)
+		t.failed = true
+		return ""
+	}
+
+	translated := normalizeSpaces(stdout.String())
+	if strings.EqualFold(translated, line) {
+		translated = ""
+	}
+	t.cache[line] = translated
+	return translated
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// siteURL resolves relative restaurant links from the listing.
+const siteURL = "https://www.kvartersmenyn.se/"
+
+// writeVCard prints a vCard 3.0 contact per restaurant, skipping restaurants
+// already written for another area or day.
+func writeVCard(w io.Writer, results []areaResult) error {
+	bw := bufio.NewWriter(w)
+	line := func(text string) {
+		bw.WriteString(foldICalLine(text))
+		bw.WriteString("\r\n")
+	}
+	seen := map[string]bool{}
+	for _, result := range results {
+		for _, r := range result.Restaurants {
+			if seen[r.ID] {
+				continue
+			}
+			seen[r.ID] = true
+
+			line("BEGIN:VCARD")
+			line("VERSION:3.0")
+			line("FN:" + escapeVCardText(r.Name))
+			line("N:" + escapeVCardText(r.Name) + ";;;;")
+			line("ORG:" + escapeVCardText(r.Name))
+			if phone := normalizePhone(r.Phone); phone != "" {
+				line("TEL;TYPE=WORK,VOICE:" + phone)
+			}
+			if r.Address != "" {
+				line("ADR;TYPE=WORK:;;" + escapeVCardText(r.Address) + ";;;;Sweden")
+			}
+			if link := absoluteLink(r.Link); link != "" {
+				line("URL:" + link)
+			}
+			line("END:VCARD")
+		}
+	}
+	return bw.Flush()
+}
+
+// escapeVCardText escapes a text value per RFC 2426, section 4. The rules
+// match iCalendar's.
+func escapeVCardText(text string) string {
+	return escapeICalText(text)
+}
+
+// normalizePhone turns a listed number like "031-123 45 67" into the
+// international form +46311234567. Numbers that already have a country code
+// keep it.
+func normalizePhone(raw string) string {
+	var digits strings.Builder
+	for _, r := range strings.TrimSpace(raw) {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+	switch {
+	case number == "":
+		return ""
+	case strings.HasPrefix(strings.TrimSpace(raw), "+"):
+		return "+" + number
+	case strings.HasPrefix(number, "00"):
+		return "+" + number[2:]
+	case strings.HasPrefix(number, "0"):
+		return "+46" + number[1:]
+	default:
+		return number
+	}
+}
+
+// absoluteLink resolves a listing link against the site.
+func absoluteLink(link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	base, _ := url.Parse(siteURL)
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// minWatchInterval keeps --watch from hammering the site.
+const minWatchInterval = time.Minute
+
+// runWatch fetches the areas live every opts.Watch and prints the filtered
+// results each cycle, or with --watch-diff only what changed. With
+// --exit-on-change it returns 0 the first time the results differ from the
+// previous cycle. Ctrl-C stops it with 130.
+func runWatch(opts Options) int {
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	nameQuery, menuQuery := effectiveQueries(opts)
+	combinedQuery := strings.TrimSpace(opts.Search)
+	// A zero TTL skips the cache; pages are still written for other runs.
+	cycleOpts := opts
+	cycleOpts.CacheTTL = 0
+
+	var previous string
+	var lastResults []areaResult
+	for {
+		ctx, cancel := context.WithTimeout(sigCtx, 15*time.Second)
+		results, complete := loadWatchCycle(ctx, cycleOpts, nameQuery, menuQuery)
+		cancel()
+		if sigCtx.Err() != nil {
+			return 130
+		}
+
+		// A cycle with failed areas is printed but not compared, so a
+		// network hiccup does not count as a change.
+		changed := false
+		if complete {
+			fingerprint := resultsFingerprint(results)
+			changed = previous != "" && fingerprint != previous
+			previous = fingerprint
+		}
+
+		switch {
+		case opts.WatchDiff && lastResults == nil:
+			// The first cycle is the baseline the changes are shown against.
+			if complete {
+				for _, result := range results {
+					printAreaText(result, opts, nameQuery, menuQuery, combinedQuery)
+				}
+				lastResults = results
+			}
+		case opts.WatchDiff:
+			if changed {
+				printWatchDiff(lastResults, results)
+			}
+			if complete {
+				lastResults = results
+			}
+		case opts.Format == formatJSON:
+			if err := writeJSON(os.Stdout, results); err != nil {
+				log.Printf("could not write JSON: %v", err)
+			}
+		default:
+			status := ""
+			if changed {
+				status = " (changed)"
+			}
+			printStyledLine(fmt.Sprintf("== %s%s ==", time.Now().Format("15:04:05"), status), styleBold)
+			fmt.Fprintln(output)
+			for _, result := range results {
+				printAreaText(result, opts, nameQuery, menuQuery, combinedQuery)
+			}
+		}
+
+		if changed && opts.ExitOnChange {
+			return 0
+		}
+		select {
+		case <-sigCtx.Done():
+			return 130
+		case <-time.After(opts.Watch):
+		}
+	}
+}
+
+// loadWatchCycle loads and filters every area once. complete is false when
+// any area failed.
+func loadWatchCycle(ctx context.Context, opts Options, nameQuery, menuQuery string) ([]areaResult, bool) {
+	var results []areaResult
+	complete := true
+	for _, area := range opts.Areas {
+		var restaurants []Restaurant
+		var info SourceInfo
+		var err error
+		if len(opts.MergeDays) > 0 {
+			restaurants, info, err = loadMergedDays(ctx, opts, area, nameQuery, menuQuery)
+		} else {
+			restaurants, info, err = loadRestaurants(ctx, opts, area)
+			restaurants = applyFilters(restaurants, opts, nameQuery, menuQuery)
+		}
+		if err != nil {
+			slog.Error(err.Error(), "area", areaLabel(area))
+			complete = false
+			continue
+		}
+		results = append(results, areaResult{Area: area, Day: opts.Day, Days: opts.MergeDays, Info: info, Restaurants: restaurants})
+	}
+	return results, complete
+}
+
+// resultsFingerprint hashes the filtered restaurants of every area.
+func resultsFingerprint(results []areaResult) string {
+	var b strings.Builder
+	for _, result := range results {
+		b.WriteString(areaLabel(result.Area))
+		b.WriteString("\x00")
+		b.WriteString(restaurantsFingerprint(result.Restaurants))
+		b.WriteString("\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// printWatchDiff prints the restaurants added, removed or with a changed
+// menu between two cycles, per area, with the new menu of added and
+// changed ones.
+func printWatchDiff(before, after []areaResult) {
+	printStyledLine(fmt.Sprintf("== %s ==", time.Now().Format("15:04:05")), styleBold)
+	previous := map[string][]Restaurant{}
+	for _, result := range before {
+		previous[areaLabel(result.Area)] = result.Restaurants
+	}
+	for _, result := range after {
+		label := areaLabel(result.Area)
+		old := map[string]Restaurant{}
+		for _, r := range previous[label] {
+			old[r.ID] = r
+		}
+		var lines []string
+		for _, r := range result.Restaurants {
+			prev, ok := old[r.ID]
+			delete(old, r.ID)
+			switch {
+			case !ok:
+				lines = append(lines, "  + "+r.Name)
+			case menuFingerprint(prev.Menu) != menuFingerprint(r.Menu):
+				lines = append(lines, "  ~ "+r.Name+" (menu changed)")
+			default:
+				continue
+			}
+			for _, line := range r.Menu {
+				lines = append(lines, "      - "+line)
+			}
+		}
+		for _, r := range previous[label] {
+			if _, gone := old[r.ID]; gone {
+				lines = append(lines, "  - "+r.Name)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		printLine(label + ":")
+		for _, line := range lines {
+			printLine(line)
+		}
+	}
+	fmt.Fprintln(output)
+}